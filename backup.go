@@ -0,0 +1,372 @@
+package gopenqa
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Version of the backup format. Backups of newer versions cannot be restored
+const BackupVersion = 1
+
+/* Backup is a snapshot of the configuration of an instance, including the comments of the job groups */
+type Backup struct {
+	Version             int               `json:"version"`
+	Remote              string            `json:"remote"`  // Instance the backup has been created from
+	Created             string            `json:"created"` // RFC3339 timestamp
+	Configuration       Configuration     `json:"-"`
+	GroupComments       map[int][]Comment `json:"-"` // Comments per job group ID
+	ParentGroupComments map[int][]Comment `json:"-"` // Comments per parent job group ID
+}
+
+// CreateBackup fetches the configuration and the job group comments of the instance
+func (i *Instance) CreateBackup() (Backup, error) {
+	backup := Backup{Version: BackupVersion, Remote: i.URL, Created: time.Now().UTC().Format(time.RFC3339)}
+	var err error
+	if backup.Configuration, err = i.ExportConfiguration(); err != nil {
+		return backup, err
+	}
+	backup.GroupComments = make(map[int][]Comment, 0)
+	for _, group := range backup.Configuration.JobGroups {
+		if backup.GroupComments[group.ID], err = i.GetJobGroupComments(group.ID); err != nil {
+			return backup, fmt.Errorf("comments of job group %d: %s", group.ID, err)
+		}
+	}
+	backup.ParentGroupComments = make(map[int][]Comment, 0)
+	for _, group := range backup.Configuration.ParentGroups {
+		if backup.ParentGroupComments[group.ID], err = i.GetParentJobGroupComments(group.ID); err != nil {
+			return backup, fmt.Errorf("comments of parent group %d: %s", group.ID, err)
+		}
+	}
+	return backup, nil
+}
+
+// RestoreBackup restores the given backup on the instance. Restoring is idempotent: existing entities are skipped (or updated with opts.Update)
+// and comments are only posted if the group has no comment with the same text yet
+func (i *Instance) RestoreBackup(backup Backup, opts ImportOptions) (ImportReport, error) {
+	if backup.Version > BackupVersion {
+		return ImportReport{}, fmt.Errorf("unsupported backup version %d", backup.Version)
+	}
+	report, err := i.ImportConfiguration(backup.Configuration, opts)
+	if err != nil {
+		return report, err
+	}
+	if err := i.restoreComments(&report, backup.GroupComments, report.JobGroups, false); err != nil {
+		return report, err
+	}
+	err = i.restoreComments(&report, backup.ParentGroupComments, report.ParentGroups, true)
+	return report, err
+}
+
+func (i *Instance) restoreComments(report *ImportReport, comments map[int][]Comment, groups map[int]int, parent bool) error {
+	entity := "group_comment"
+	if parent {
+		entity = "parent_group_comment"
+	}
+	ids := make([]int, 0)
+	for id := range comments {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	for _, sourceID := range ids {
+		id, ok := groups[sourceID]
+		if !ok || len(comments[sourceID]) == 0 {
+			// Group not restored
+			continue
+		}
+		var existing []Comment
+		var err error
+		if parent {
+			existing, err = i.GetParentJobGroupComments(id)
+		} else {
+			existing, err = i.GetJobGroupComments(id)
+		}
+		if err != nil {
+			return err
+		}
+		for _, comment := range comments[sourceID] {
+			action := ImportCreated
+			commentID := 0
+			for _, e := range existing {
+				if e.Text == comment.Text {
+					action = ImportSkipped
+					commentID = e.ID
+				}
+			}
+			if action == ImportCreated {
+				if parent {
+					commentID, err = i.PostParentJobGroupComment(id, comment.Text)
+				} else {
+					commentID, err = i.PostJobGroupComment(id, comment.Text)
+				}
+				if err != nil {
+					return fmt.Errorf("comment %d of group %d: %s", comment.ID, id, err)
+				}
+			}
+			report.add(entity, fmt.Sprintf("%d", id), comment.ID, commentID, action)
+		}
+	}
+	return nil
+}
+
+/* Backup files */
+
+// isArchive returns true, if the given path refers to a tar.gz archive instead of a directory
+func isArchive(path string) bool {
+	return strings.HasSuffix(path, ".tar.gz") || strings.HasSuffix(path, ".tgz")
+}
+
+// files returns the backup as files (relative path -> content)
+func (b *Backup) files() (map[string][]byte, error) {
+	files := make(map[string][]byte, 0)
+	add := func(name string, data interface{}) error {
+		buf, err := json.MarshalIndent(data, "", "  ")
+		if err != nil {
+			return err
+		}
+		files[name] = append(buf, '\n')
+		return nil
+	}
+	cf := b.Configuration
+	if err := add("backup.json", b); err != nil {
+		return files, err
+	}
+	if err := add("machines.json", cf.Machines); err != nil {
+		return files, err
+	}
+	if err := add("products.json", cf.Products); err != nil {
+		return files, err
+	}
+	if err := add("test_suites.json", cf.TestSuites); err != nil {
+		return files, err
+	}
+	if err := add("parent_groups.json", cf.ParentGroups); err != nil {
+		return files, err
+	}
+	if err := add("job_groups.json", cf.JobGroups); err != nil {
+		return files, err
+	}
+	for id, yaml := range cf.SchedulingYAML {
+		files[fmt.Sprintf("scheduling/%d.yaml", id)] = []byte(yaml)
+	}
+	for id, comments := range b.GroupComments {
+		if err := add(fmt.Sprintf("comments/job_groups/%d.json", id), comments); err != nil {
+			return files, err
+		}
+	}
+	for id, comments := range b.ParentGroupComments {
+		if err := add(fmt.Sprintf("comments/parent_groups/%d.json", id), comments); err != nil {
+			return files, err
+		}
+	}
+	return files, nil
+}
+
+// parseBackup parses the backup from the given files (relative path -> content)
+func parseBackup(files map[string][]byte) (Backup, error) {
+	var backup Backup
+	buf, ok := files["backup.json"]
+	if !ok {
+		return backup, fmt.Errorf("not a backup: backup.json missing")
+	}
+	if err := json.Unmarshal(buf, &backup); err != nil {
+		return backup, fmt.Errorf("backup.json: %s", err)
+	}
+	if backup.Version < 1 || backup.Version > BackupVersion {
+		return backup, fmt.Errorf("unsupported backup version %d", backup.Version)
+	}
+	cf := &backup.Configuration
+	entities := map[string]interface{}{
+		"machines.json":      &cf.Machines,
+		"products.json":      &cf.Products,
+		"test_suites.json":   &cf.TestSuites,
+		"parent_groups.json": &cf.ParentGroups,
+		"job_groups.json":    &cf.JobGroups,
+	}
+	for name, dst := range entities {
+		if buf, ok := files[name]; ok {
+			if err := json.Unmarshal(buf, dst); err != nil {
+				return backup, fmt.Errorf("%s: %s", name, err)
+			}
+		}
+	}
+	cf.SchedulingYAML = make(map[int]string, 0)
+	backup.GroupComments = make(map[int][]Comment, 0)
+	backup.ParentGroupComments = make(map[int][]Comment, 0)
+	for name, buf := range files {
+		sep := strings.LastIndex(name, "/")
+		dir, base := name[:sep+1], name[sep+1:]
+		ext := filepath.Ext(base)
+		id, err := strconv.Atoi(strings.TrimSuffix(base, ext))
+		if err != nil {
+			continue
+		}
+		if dir == "scheduling/" && ext == ".yaml" {
+			cf.SchedulingYAML[id] = string(buf)
+		} else if (dir == "comments/job_groups/" || dir == "comments/parent_groups/") && ext == ".json" {
+			var comments []Comment
+			if err := json.Unmarshal(buf, &comments); err != nil {
+				return backup, fmt.Errorf("%s: %s", name, err)
+			}
+			if dir == "comments/job_groups/" {
+				backup.GroupComments[id] = comments
+			} else {
+				backup.ParentGroupComments[id] = comments
+			}
+		}
+	}
+	return backup, nil
+}
+
+// Write the backup to the given path. Paths ending in .tar.gz or .tgz create an archive, otherwise a directory
+// An existing directory must be empty, as stale files would be restored together with the backup. Existing archives are not overwritten
+func (b *Backup) Write(path string) error {
+	files, err := b.files()
+	if err != nil {
+		return err
+	}
+	if isArchive(path) {
+		return writeArchive(path, files)
+	}
+	if entries, err := os.ReadDir(path); err == nil && len(entries) > 0 {
+		return fmt.Errorf("%s is not empty", path)
+	} else if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	// Write into a temporary directory first, so that a failed backup leaves no partial directory behind
+	tmp, err := os.MkdirTemp(filepath.Dir(filepath.Clean(path)), ".backup-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmp)
+	if err := os.Chmod(tmp, 0755); err != nil {
+		return err
+	}
+	for name, buf := range files {
+		filename := filepath.Join(tmp, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(filename, buf, 0644); err != nil {
+			return err
+		}
+	}
+	// Replace the empty directory, if present
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// writeArchive writes the files to a new tar.gz archive. An existing archive is never overwritten and a failure leaves no partial archive behind
+func writeArchive(path string, files map[string][]byte) error {
+	if _, err := os.Lstat(path); err == nil {
+		return fmt.Errorf("%s already exists", path)
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+	file, err := os.CreateTemp(filepath.Dir(filepath.Clean(path)), ".backup-*")
+	if err != nil {
+		return err
+	}
+	if err := writeTarGz(file, files); err != nil {
+		file.Close()
+		os.Remove(file.Name())
+		return err
+	}
+	if err := file.Close(); err != nil {
+		os.Remove(file.Name())
+		return err
+	}
+	if err := os.Chmod(file.Name(), 0644); err != nil {
+		os.Remove(file.Name())
+		return err
+	}
+	if err := os.Rename(file.Name(), path); err != nil {
+		os.Remove(file.Name())
+		return err
+	}
+	return nil
+}
+
+// writeTarGz writes the files sorted by name as tar.gz to the given writer
+func writeTarGz(w io.Writer, files map[string][]byte) error {
+	zw := gzip.NewWriter(w)
+	tw := tar.NewWriter(zw)
+	names := make([]string, 0)
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		header := tar.Header{Name: name, Mode: 0644, Size: int64(len(files[name])), ModTime: time.Now()}
+		if err := tw.WriteHeader(&header); err != nil {
+			return err
+		}
+		if _, err := tw.Write(files[name]); err != nil {
+			return err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+// ReadBackup reads a backup from the given directory or tar.gz archive
+func ReadBackup(path string) (Backup, error) {
+	files := make(map[string][]byte, 0)
+	if isArchive(path) {
+		file, err := os.Open(path)
+		if err != nil {
+			return Backup{}, err
+		}
+		defer file.Close()
+		zr, err := gzip.NewReader(file)
+		if err != nil {
+			return Backup{}, err
+		}
+		tr := tar.NewReader(zr)
+		for {
+			header, err := tr.Next()
+			if err == io.EOF {
+				break
+			} else if err != nil {
+				return Backup{}, err
+			}
+			if header.Typeflag != tar.TypeReg {
+				continue
+			}
+			buf, err := io.ReadAll(tr)
+			if err != nil {
+				return Backup{}, err
+			}
+			files[strings.TrimPrefix(header.Name, "./")] = buf
+		}
+	} else {
+		err := filepath.Walk(path, func(filename string, info os.FileInfo, err error) error {
+			if err != nil || info.IsDir() {
+				return err
+			}
+			name, err := filepath.Rel(path, filename)
+			if err != nil {
+				return err
+			}
+			buf, err := os.ReadFile(filename)
+			files[filepath.ToSlash(name)] = buf
+			return err
+		})
+		if err != nil {
+			return Backup{}, err
+		}
+	}
+	return parseBackup(files)
+}
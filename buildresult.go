@@ -0,0 +1,150 @@
+package gopenqa
+
+import (
+	"encoding/json"
+	"sort"
+	"strconv"
+)
+
+/* Build result as shown in the build results overview of a job group */
+type BuildResult struct {
+	Build      string    `json:"build"`
+	Version    string    `json:"version"`
+	Date       string    `json:"date"`   // Date of the most recent job in this build
+	Oldest     string    `json:"oldest"` // Date of the oldest job in this build
+	Passed     int       `json:"passed"`
+	Failed     int       `json:"failed"`
+	Softfailed int       `json:"softfailed"`
+	Running    int       `json:"running"`    // Only present on instances that report running jobs separately
+	Scheduled  int       `json:"scheduled"`  // Only present on instances that report scheduled jobs separately
+	Unfinished int       `json:"unfinished"` // Scheduled and running jobs, as reported by openQA
+	Skipped    int       `json:"skipped"`
+	Labeled    int       `json:"labeled"` // Failed jobs with a bug reference or label
+	Total      int       `json:"total"`
+	Reviewed   bool      `json:"reviewed"` // All failures of the build are labeled
+	AllPassed  bool      `json:"all_passed"`
+	Distris    []string  `json:"distris"`
+	Tag        *BuildTag `json:"tag,omitempty"` // Tag of the build, if tagged
+}
+
+/* Tag of a build, e.g. set via "tag:BUILD:important:note" comments */
+type BuildTag struct {
+	Type        string `json:"type"`
+	Description string `json:"description"`
+	Version     string `json:"version"`
+}
+
+/* Build results overview of a job group */
+type BuildResults struct {
+	Group   JobGroup      `json:"group"`
+	MaxJobs int           `json:"max_jobs"`
+	Builds  []BuildResult `json:"build_results"`
+}
+
+/* IsTagged returns true, if the build has a tag */
+func (b *BuildResult) IsTagged() bool {
+	return b.Tag != nil && b.Tag.Type != ""
+}
+
+/* Pending returns the number of jobs which are not yet finished */
+func (b *BuildResult) Pending() int {
+	if b.Unfinished > 0 {
+		return b.Unfinished
+	}
+	return b.Running + b.Scheduled
+}
+
+/* Finished returns true, if all jobs of this build are finished */
+func (b *BuildResult) Finished() bool {
+	return b.Pending() == 0
+}
+
+// openQA renders booleans and counters inconsistently (1, "1", "", true, null), so we parse them manually
+func unboxInt(v interface{}) int {
+	switch unboxed := v.(type) {
+	case float64:
+		return int(unboxed)
+	case string:
+		i, _ := strconv.Atoi(unboxed) // ignore error
+		return i
+	case bool:
+		if unboxed {
+			return 1
+		}
+	}
+	return 0
+}
+
+func unboxBool(v interface{}) bool {
+	switch unboxed := v.(type) {
+	case bool:
+		return unboxed
+	case float64:
+		return unboxed != 0
+	case string:
+		return unboxed != "" && unboxed != "0"
+	}
+	return false
+}
+
+func (b *BuildResult) UnmarshalJSON(data []byte) error {
+	type IBuildResult struct {
+		Build      interface{} `json:"build"`
+		Version    string      `json:"version"`
+		Date       string      `json:"date"`
+		Oldest     string      `json:"oldest"`
+		Passed     interface{} `json:"passed"`
+		Failed     interface{} `json:"failed"`
+		Softfailed interface{} `json:"softfailed"`
+		Running    interface{} `json:"running"`
+		Scheduled  interface{} `json:"scheduled"`
+		Unfinished interface{} `json:"unfinished"`
+		Skipped    interface{} `json:"skipped"`
+		Labeled    interface{} `json:"labeled"`
+		Total      interface{} `json:"total"`
+		Reviewed   interface{} `json:"reviewed"`
+		AllPassed  interface{} `json:"all_passed"`
+		Distris    interface{} `json:"distris"` // either a list or a dict with the distris as keys
+		Tag        *BuildTag   `json:"tag"`
+	}
+	var ib IBuildResult
+	if err := json.Unmarshal(data, &ib); err != nil {
+		return err
+	}
+	switch build := ib.Build.(type) {
+	case string:
+		b.Build = build
+	case float64:
+		b.Build = strconv.FormatFloat(build, 'f', -1, 64)
+	}
+	b.Version = ib.Version
+	b.Date = ib.Date
+	b.Oldest = ib.Oldest
+	b.Passed = unboxInt(ib.Passed)
+	b.Failed = unboxInt(ib.Failed)
+	b.Softfailed = unboxInt(ib.Softfailed)
+	b.Running = unboxInt(ib.Running)
+	b.Scheduled = unboxInt(ib.Scheduled)
+	b.Unfinished = unboxInt(ib.Unfinished)
+	b.Skipped = unboxInt(ib.Skipped)
+	b.Labeled = unboxInt(ib.Labeled)
+	b.Total = unboxInt(ib.Total)
+	b.Reviewed = unboxBool(ib.Reviewed)
+	b.AllPassed = unboxBool(ib.AllPassed)
+	b.Tag = ib.Tag
+	b.Distris = make([]string, 0)
+	switch distris := ib.Distris.(type) {
+	case []interface{}:
+		for _, distri := range distris {
+			if s, ok := distri.(string); ok {
+				b.Distris = append(b.Distris, s)
+			}
+		}
+	case map[string]interface{}:
+		for distri := range distris {
+			b.Distris = append(b.Distris, distri)
+		}
+		sort.Strings(b.Distris)
+	}
+	return nil
+}
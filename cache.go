@@ -0,0 +1,381 @@
+package gopenqa
+
+import (
+	"container/list"
+	"crypto/sha1"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Default time-to-live of cached responses per endpoint (first path element after /api/v1/)
+// Responses of other endpoints are only cached if the server supports revalidation, and then revalidated on each request
+var DefaultCacheTTLs = map[string]time.Duration{
+	"job_groups":               5 * time.Minute,
+	"parent_groups":            5 * time.Minute,
+	"machines":                 5 * time.Minute,
+	"products":                 5 * time.Minute,
+	"test_suites":              5 * time.Minute,
+	"job_templates":            5 * time.Minute,
+	"job_templates_scheduling": 5 * time.Minute,
+}
+
+// Endpoints whose responses may change with writes on another endpoint (e.g. job templates contain the machine)
+// Writes invalidate their own endpoint and the endpoints listed here
+var cacheDependencies = map[string][]string{
+	"jobs":                     {"job_groups"}, // Jobs of a job group
+	"isos":                     {"jobs", "job_groups"},
+	"job_groups":               {"parent_groups", "job_templates", "job_templates_scheduling"},
+	"parent_groups":            {"job_groups"},
+	"machines":                 {"job_templates", "job_templates_scheduling"},
+	"products":                 {"job_templates", "job_templates_scheduling"},
+	"test_suites":              {"job_templates", "job_templates_scheduling"},
+	"job_templates":            {"job_templates_scheduling"},
+	"job_templates_scheduling": {"job_templates"},
+}
+
+// Matches the URL of a single job
+var singleJobURL = regexp.MustCompile(`/api/v1/jobs/[0-9]+$`)
+
+/* Cache stores the responses of GET requests of instances, see Instance.SetCache
+ * Entries are kept in memory up to the given capacity, least recently used entries are evicted first.
+ * If a directory is given, entries are also stored on disk and survive restarts. The directory is not limited in size.
+ * Expired entries are revalidated via ETag or Last-Modified, if the server provided them.
+ * Finished jobs never expire, so restarts by other clients are only noticed after Invalidate("jobs"). Following clones (e.g. GetJobFollow) revalidates them.
+ * Entries are kept per API key, as responses may depend on the permissions of the user.
+ * POST, PUT and DELETE requests of the instance invalidate the entries of the endpoint and the endpoints depending on it
+ */
+type Cache struct {
+	capacity int
+	dir      string
+	ttls     map[string]time.Duration
+	mutex    sync.Mutex
+	entries  map[string]*list.Element
+	lru      *list.List        // Values are *cacheEntry, most recently used first
+	files    map[string]string // Endpoints of the files in the cache directory by filename. nil until the directory has been read
+}
+
+// cacheEntry is a cached response. Stored as JSON in the cache directory
+type cacheEntry struct {
+	Key          string    `json:"key"` // URL and hashed API key of the request
+	Endpoint     string    `json:"endpoint"`
+	Body         []byte    `json:"body"`
+	ETag         string    `json:"etag,omitempty"`
+	LastModified string    `json:"last_modified,omitempty"`
+	Expires      time.Time `json:"expires"`
+	Immutable    bool      `json:"immutable,omitempty"` // Finished jobs do not change anymore
+}
+
+/* NewCache creates a cache with the DefaultCacheTTLs, which keeps up to capacity responses in memory. If dir is not empty, the responses are also stored in the given directory */
+func NewCache(capacity int, dir string) *Cache {
+	ttls := make(map[string]time.Duration, 0)
+	for endpoint, ttl := range DefaultCacheTTLs {
+		ttls[endpoint] = ttl
+	}
+	return &Cache{capacity: capacity, dir: dir, ttls: ttls, entries: make(map[string]*list.Element, 0), lru: list.New()}
+}
+
+// SetTTL sets the time-to-live of the responses of the given endpoint (e.g. "job_groups" or "workers"). 0 revalidates each request
+func (c *Cache) SetTTL(endpoint string, ttl time.Duration) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.ttls[endpoint] = ttl
+}
+
+// Invalidate removes all entries of the given endpoint, including finished jobs. An empty endpoint clears the whole cache
+func (c *Cache) Invalidate(endpoint string) {
+	c.remove(func(e string) bool {
+		return endpoint == "" || e == endpoint
+	})
+}
+
+// Len returns the number of entries in memory
+func (c *Cache) Len() int {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.lru.Len()
+}
+
+// cacheEndpoint returns the endpoint of the given url, i.e. the first path element after /api/v1/
+func cacheEndpoint(url string) string {
+	path := url_path(url)
+	if i := strings.Index(path, "?"); i >= 0 {
+		path = path[:i]
+	}
+	i := strings.Index(path, "/api/v1/")
+	if i < 0 {
+		return ""
+	}
+	endpoint := path[i+len("/api/v1/"):]
+	if i := strings.Index(endpoint, "/"); i >= 0 {
+		endpoint = endpoint[:i]
+	}
+	return endpoint
+}
+
+// isFinishedJob returns true if the given response is a single job that is done or cancelled
+func isFinishedJob(url string, body []byte) bool {
+	if !singleJobURL.MatchString(url) {
+		return false
+	}
+	var response struct {
+		Job struct {
+			State string `json:"state"`
+		} `json:"job"`
+	}
+	if err := json.Unmarshal(body, &response); err != nil {
+		return false
+	}
+	return response.Job.State == "done" || response.Job.State == "cancelled"
+}
+
+// fresh returns true if the entry can be used without asking the server. With latest, finished jobs are revalidated as well
+func (e *cacheEntry) fresh(latest bool) bool {
+	return (e.Immutable && !latest) || time.Now().Before(e.Expires)
+}
+
+// revalidatable returns true if the server can tell if the entry is still valid
+func (e *cacheEntry) revalidatable() bool {
+	return e.ETag != "" || e.LastModified != ""
+}
+
+// ttl returns the time-to-live of the responses of the given endpoint
+func (c *Cache) ttl(endpoint string) time.Duration {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.ttls[endpoint]
+}
+
+// filename returns the name of the file of the given key in the cache directory. The endpoint is part of the name, so that invalidation does not need to read the files
+func filename(endpoint string, key string) string {
+	return fmt.Sprintf("%s-%x.json", endpoint, sha1.Sum([]byte(key)))
+}
+
+// loadFiles reads the filenames of the cache directory once. Files written later by other processes sharing the directory are not noticed
+func (c *Cache) loadFiles() {
+	c.mutex.Lock()
+	loaded := c.files != nil
+	c.mutex.Unlock()
+	if loaded || c.dir == "" {
+		return
+	}
+	files := make(map[string]string, 0)
+	entries, _ := os.ReadDir(c.dir) // A missing directory is an empty cache
+	for _, file := range entries {
+		name := strings.TrimSuffix(file.Name(), ".json")
+		if i := strings.LastIndex(name, "-"); !file.IsDir() && name != file.Name() && i >= 0 {
+			files[file.Name()] = name[:i]
+		}
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.files == nil {
+		c.files = files
+	}
+}
+
+// lookup returns a copy of the entry of the given key and endpoint from memory or disk
+func (c *Cache) lookup(key string, endpoint string) (cacheEntry, bool) {
+	c.loadFiles()
+	name := filename(endpoint, key)
+	c.mutex.Lock()
+	if element, ok := c.entries[key]; ok {
+		c.lru.MoveToFront(element)
+		entry := *element.Value.(*cacheEntry)
+		c.mutex.Unlock()
+		return entry, true
+	}
+	_, stored := c.files[name]
+	c.mutex.Unlock()
+	if !stored {
+		return cacheEntry{}, false
+	}
+	var entry cacheEntry
+	buf, err := os.ReadFile(filepath.Join(c.dir, name))
+	if err != nil || json.Unmarshal(buf, &entry) != nil || entry.Key != key {
+		return cacheEntry{}, false
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if _, stored := c.files[name]; !stored {
+		// Invalidated while reading
+		return cacheEntry{}, false
+	}
+	c.insert(entry)
+	return entry, true
+}
+
+// store adds or replaces the given entry
+func (c *Cache) store(entry cacheEntry) {
+	c.loadFiles()
+	name := filename(entry.Endpoint, entry.Key)
+	c.mutex.Lock()
+	c.insert(entry)
+	if c.dir != "" {
+		c.files[name] = entry.Endpoint
+	}
+	c.mutex.Unlock()
+	if c.dir == "" || c.write(name, entry) == nil {
+		return
+	}
+	// The cache is best effort, a failure to persist an entry only affects later runs
+	c.mutex.Lock()
+	delete(c.files, name)
+	c.mutex.Unlock()
+}
+
+// write stores the entry in the given file of the cache directory. The file is replaced atomically, so that readers never see partial entries
+func (c *Cache) write(name string, entry cacheEntry) error {
+	buf, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(c.dir, 0755); err != nil {
+		return err
+	}
+	file, err := os.CreateTemp(c.dir, "."+name)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(buf); err != nil {
+		file.Close()
+		os.Remove(file.Name())
+		return err
+	}
+	if err := file.Close(); err != nil {
+		os.Remove(file.Name())
+		return err
+	}
+	if err := os.Rename(file.Name(), filepath.Join(c.dir, name)); err != nil {
+		os.Remove(file.Name())
+		return err
+	}
+	c.mutex.Lock()
+	_, stored := c.files[name]
+	c.mutex.Unlock()
+	if !stored {
+		// Invalidated while writing
+		os.Remove(filepath.Join(c.dir, name))
+	}
+	return nil
+}
+
+// insert adds the entry to memory and evicts the least recently used entries. Must be called with the mutex held
+func (c *Cache) insert(entry cacheEntry) {
+	if element, ok := c.entries[entry.Key]; ok {
+		element.Value = &entry
+		c.lru.MoveToFront(element)
+		return
+	}
+	c.entries[entry.Key] = c.lru.PushFront(&entry)
+	for c.capacity > 0 && c.lru.Len() > c.capacity {
+		oldest := c.lru.Back()
+		c.lru.Remove(oldest)
+		delete(c.entries, oldest.Value.(*cacheEntry).Key)
+	}
+}
+
+// remove deletes all entries of the endpoints matching the given predicate from memory and disk
+func (c *Cache) remove(match func(endpoint string) bool) {
+	c.loadFiles()
+	removed := make([]string, 0)
+	c.mutex.Lock()
+	for element := c.lru.Front(); element != nil; {
+		next := element.Next()
+		if entry := element.Value.(*cacheEntry); match(entry.Endpoint) {
+			c.lru.Remove(element)
+			delete(c.entries, entry.Key)
+		}
+		element = next
+	}
+	for name, endpoint := range c.files {
+		if match(endpoint) {
+			delete(c.files, name)
+			removed = append(removed, name)
+		}
+	}
+	c.mutex.Unlock()
+	for _, name := range removed {
+		os.Remove(filepath.Join(c.dir, name))
+	}
+}
+
+// invalidateWrite removes the entries that might be changed by a POST, PUT or DELETE request on the given url, see cacheDependencies
+// Writes on jobs (e.g. deleting or restarting) also remove finished jobs
+func (c *Cache) invalidateWrite(url string) {
+	endpoint := cacheEndpoint(url)
+	c.remove(func(e string) bool {
+		if e == endpoint {
+			return true
+		}
+		for _, dependency := range cacheDependencies[endpoint] {
+			if e == dependency {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+/* SetCache enables caching of GET requests with the given cache. The cache may be shared between instances. nil disables caching */
+func (i *Instance) SetCache(cache *Cache) {
+	i.cache = cache
+}
+
+// cacheKey returns the key of the given url in the cache. The API key is hashed, so that it is not stored in the cache directory
+func (i *Instance) cacheKey(url string) string {
+	if i.apikey == "" {
+		return url
+	}
+	return fmt.Sprintf("%s %x", url, sha1.Sum([]byte(i.apikey)))
+}
+
+// cachedGet performs a GET request on the given url, using and updating the cache. With latest, finished jobs are revalidated, see getLatest
+func (i *Instance) cachedGet(url string, latest bool) ([]byte, error) {
+	endpoint := cacheEndpoint(url)
+	key := i.cacheKey(url)
+	ttl := i.cache.ttl(endpoint)
+	entry, cached := i.cache.lookup(key, endpoint)
+	if cached && entry.fresh(latest) {
+		if i.verbose {
+			fmt.Fprintf(os.Stderr, "cached: %s\n", url)
+		}
+		return entry.Body, nil
+	}
+	header := make(map[string]string, 0)
+	if cached {
+		if entry.ETag != "" {
+			header["If-None-Match"] = entry.ETag
+		}
+		if entry.LastModified != "" {
+			header["If-Modified-Since"] = entry.LastModified
+		}
+	}
+	status, response, buf, err := i.sendHeader("GET", url, "", header, nil)
+	if err != nil {
+		return buf, err
+	}
+	if cached && status == 304 {
+		if i.verbose {
+			fmt.Fprintf(os.Stderr, "revalidated: %s\n", url)
+		}
+		entry.Expires = time.Now().Add(ttl)
+		i.cache.store(entry)
+		return entry.Body, nil
+	}
+	if buf, err = i.checkStatus(status, buf); err != nil {
+		return buf, err
+	}
+	entry = cacheEntry{Key: key, Endpoint: endpoint, Body: buf, ETag: response.Get("ETag"), LastModified: response.Get("Last-Modified")}
+	entry.Expires = time.Now().Add(ttl)
+	entry.Immutable = isFinishedJob(url, buf)
+	if ttl > 0 || entry.Immutable || entry.revalidatable() {
+		i.cache.store(entry)
+	}
+	return buf, nil
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// apiPath returns the path of the given API route. Routes without /api/ prefix are relative to /api/v1
+func apiPath(path string) string {
+	trimmed := strings.TrimPrefix(path, "/")
+	if strings.HasPrefix(trimmed, "api/") {
+		return "/" + trimmed
+	}
+	return "/api/v1/" + trimmed
+}
+
+// apiParams splits KEY=VALUE parameters into form values and JSON values. KEY:=VALUE passes the value as raw JSON
+func apiParams(params []string, asJSON bool) (url.Values, map[string]interface{}, error) {
+	values := url.Values{}
+	object := make(map[string]interface{}, 0)
+	for _, param := range params {
+		i := strings.Index(param, "=")
+		if i <= 0 {
+			return values, object, fmt.Errorf("invalid parameter: %s", param)
+		}
+		key, value := param[:i], param[i+1:]
+		if strings.HasSuffix(key, ":") {
+			key = strings.TrimSuffix(key, ":")
+			if !asJSON {
+				return values, object, fmt.Errorf("raw JSON parameters require --json: %s", param)
+			}
+			var raw interface{}
+			if err := json.Unmarshal([]byte(value), &raw); err != nil {
+				return values, object, fmt.Errorf("invalid JSON value of %s: %s", key, err)
+			}
+			object[key] = raw
+			continue
+		}
+		values.Add(key, value)
+		object[key] = value
+	}
+	return values, object, nil
+}
+
+// readBody reads the body given by --data. @FILE reads the file, @- reads stdin
+func readBody(data string) ([]byte, error) {
+	if data == "@-" {
+		return io.ReadAll(os.Stdin)
+	} else if strings.HasPrefix(data, "@") {
+		return os.ReadFile(data[1:])
+	}
+	return []byte(data), nil
+}
+
+// printResponse prints the response body. JSON is pretty-printed or printed in the configured output format
+func printResponse(buf []byte) error {
+	var data interface{}
+	decoder := json.NewDecoder(bytes.NewReader(buf))
+	decoder.UseNumber()
+	if err := decoder.Decode(&data); err != nil {
+		// Not JSON, print as is
+		os.Stdout.Write(buf)
+		if len(buf) > 0 && buf[len(buf)-1] != '\n' {
+			fmt.Println()
+		}
+		return nil
+	}
+	if cf.Output == "" {
+		buf, err := json.MarshalIndent(data, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(buf))
+		return nil
+	}
+	return printData(data)
+}
+
+/* Perform a raw request on the API of the instance. Parameters are sent as query for GET and DELETE requests and as body otherwise */
+func runAPI(args *Args) error {
+	if len(args.Positional) == 0 {
+		return fmt.Errorf("missing path")
+	}
+	path := apiPath(args.Positional[0])
+	method := "GET"
+	if args.Has("method") {
+		method = strings.ToUpper(args.Value("method"))
+	}
+	asJSON := args.Has("json")
+	values, object, err := apiParams(args.Positional[1:], asJSON)
+	if err != nil {
+		return err
+	}
+
+	var body []byte
+	contentType := ""
+	if args.Has("data") {
+		if len(values) > 0 || len(object) > 0 {
+			return fmt.Errorf("either --data or parameters can be given")
+		}
+		if body, err = readBody(args.Value("data")); err != nil {
+			return err
+		}
+		contentType = "application/x-www-form-urlencoded"
+		if asJSON {
+			contentType = "application/json"
+		}
+	} else if len(object) > 0 {
+		if method == "GET" || method == "DELETE" {
+			if asJSON {
+				return fmt.Errorf("JSON bodies are not supported for %s requests", method)
+			}
+			separator := "?"
+			if strings.Contains(path, "?") {
+				separator = "&"
+			}
+			path += separator + values.Encode()
+		} else if asJSON {
+			if body, err = json.Marshal(object); err != nil {
+				return err
+			}
+			contentType = "application/json"
+		} else {
+			body = []byte(values.Encode())
+			contentType = "application/x-www-form-urlencoded"
+		}
+	}
+
+	if cf.Verbose {
+		fmt.Fprintf(os.Stderr, "%s %s%s\n", method, instance.URL, path)
+	}
+	status, buf, err := instance.Do(method, path, contentType, body)
+	if err != nil {
+		return err
+	}
+	if err := printResponse(buf); err != nil {
+		return err
+	}
+	if status < 200 || status >= 300 {
+		return fmt.Errorf("http status code %d", status)
+	}
+	return nil
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/os-autoinst/gopenqa"
+)
+
+/* Write a backup of the instance configuration into a directory or archive */
+func runBackup(args *Args) error {
+	if len(args.Positional) != 1 {
+		return fmt.Errorf("usage: backup DIR|FILE.tar.gz")
+	}
+	path := args.Positional[0]
+	backup, err := instance.CreateBackup()
+	if err != nil {
+		return err
+	}
+	if err := backup.Write(path); err != nil {
+		return err
+	}
+	config := backup.Configuration
+	fmt.Printf("Backup of %s written to %s: %d machines, %d products, %d test suites, %d parent groups, %d job groups\n", backup.Remote, path, len(config.Machines), len(config.Products), len(config.TestSuites), len(config.ParentGroups), len(config.JobGroups))
+	return nil
+}
+
+/* Restore a backup from a directory or archive */
+func runRestore(args *Args) error {
+	var opts gopenqa.ImportOptions
+	if len(args.Positional) != 1 {
+		return fmt.Errorf("usage: restore [--update] [--group IDS] DIR|FILE.tar.gz")
+	}
+	path := args.Positional[0]
+	opts.Update = args.Has("update")
+	if args.Has("group") {
+		ids, err := parseGroupIDs(args.Values("group"))
+		if err != nil {
+			return err
+		}
+		opts.JobGroups = ids
+	}
+
+	backup, err := gopenqa.ReadBackup(path)
+	if err != nil {
+		return err
+	}
+	if opts.Update && !cf.NoPrompt {
+		fmt.Printf("Existing entities on %s will be overwritten with the backup of %s from %s.\n", instance.URL, backup.Remote, backup.Created)
+		if prompt("Type uppercase 'yes' to continue: ") != "YES" {
+			return fmt.Errorf("cancelled")
+		}
+	}
+	report, err := instance.RestoreBackup(backup, opts)
+	printImportReport(report)
+	return err
+}
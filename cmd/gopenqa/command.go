@@ -0,0 +1,314 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+/* Flag of a command. Flags are given as --NAME (or -SHORT) and take a value, if Value is set */
+type Flag struct {
+	Name     string   // Long name without dashes, also the key in Args
+	Short    string   // Optional single letter name
+	Aliases  []string // Further long names
+	Value    string   // Placeholder of the value in the help text. Empty for boolean flags
+	Help     string
+	Complete string // Completion of the value, see completeKind
+}
+
+/* Command is a node of the command tree. Commands either run something or have subcommands (verbs) */
+type Command struct {
+	Name     string
+	Aliases  []string
+	Args     string // Positional arguments in the help text, e.g. "IDS..."
+	Help     string
+	Flags    []Flag
+	Commands []*Command
+	Default  string // Subcommand to run, if none is given
+	Complete string // Completion of the positional arguments, see completeKind
+	Hidden   bool   // Not shown in help and completions
+	Epilog   string // Printed at the end of the help text
+	Run      func(args *Args) error
+	parent   *Command
+}
+
+/* Args are the parsed arguments of a command */
+type Args struct {
+	Positional []string
+	flags      map[string][]string
+}
+
+// Has returns true if the given flag has been set
+func (a *Args) Has(name string) bool {
+	_, ok := a.flags[name]
+	return ok
+}
+
+// Value returns the last value of the given flag or an empty string
+func (a *Args) Value(name string) string {
+	values := a.flags[name]
+	if len(values) == 0 {
+		return ""
+	}
+	return values[len(values)-1]
+}
+
+// Values returns all values of the given flag. Comma separated values are split
+func (a *Args) Values(name string) []string {
+	values := make([]string, 0)
+	for _, value := range a.flags[name] {
+		values = append(values, strings.Split(value, ",")...)
+	}
+	return values
+}
+
+// Link the parents of all subcommands
+func (c *Command) init() *Command {
+	for _, sub := range c.Commands {
+		sub.parent = c
+		sub.init()
+	}
+	return c
+}
+
+// Path returns the full name of the command, e.g. "gopenqa worker delete"
+func (c *Command) Path() string {
+	if c.parent == nil {
+		return c.Name
+	}
+	return c.parent.Path() + " " + c.Name
+}
+
+// Command returns the subcommand with the given name or alias. Names are case insensitive, so that "GET" and "get" are the same verb
+func (c *Command) Command(name string) *Command {
+	name = strings.ToLower(name)
+	for _, sub := range c.Commands {
+		if sub.Name == name {
+			return sub
+		}
+		for _, alias := range sub.Aliases {
+			if alias == name {
+				return sub
+			}
+		}
+	}
+	return nil
+}
+
+// Flag returns the flag with the given long (or short) name of this command or of any parent command
+func (c *Command) Flag(name string, long bool) *Flag {
+	for cmd := c; cmd != nil; cmd = cmd.parent {
+		for i := range cmd.Flags {
+			flag := &cmd.Flags[i]
+			if !long {
+				if flag.Short != "" && flag.Short == name {
+					return flag
+				}
+				continue
+			}
+			if flag.Name == name {
+				return flag
+			}
+			for _, alias := range flag.Aliases {
+				if alias == name {
+					return flag
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// isInteger returns true if the given argument is a positive number
+func isInteger(arg string) bool {
+	if arg == "" {
+		return false
+	}
+	for _, c := range arg {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+/* parser walks over the arguments and keeps track of the selected command */
+type parser struct {
+	cmd     *Command
+	args    Args
+	flag    *Flag  // Flag waiting for its value
+	flagArg string // Flag waiting for its value as given on the command line
+	literal bool   // All following arguments are positional (after "--")
+}
+
+func newParser(root *Command) *parser {
+	return &parser{cmd: root, args: Args{Positional: make([]string, 0), flags: make(map[string][]string, 0)}}
+}
+
+// next consumes a single argument
+func (p *parser) next(arg string) error {
+	if p.flag != nil {
+		p.args.flags[p.flag.Name] = append(p.args.flags[p.flag.Name], arg)
+		p.flag = nil
+		return nil
+	}
+	if !p.literal && arg == "--" {
+		p.literal = true
+		return nil
+	}
+	if !p.literal && len(arg) > 1 && arg[0] == '-' {
+		long := strings.HasPrefix(arg, "--")
+		name := strings.TrimLeft(arg, "-")
+		value, hasValue := "", false
+		if i := strings.Index(name, "="); long && i > 0 {
+			name, value, hasValue = name[:i], name[i+1:], true
+		}
+		flag := p.cmd.Flag(name, long)
+		if flag == nil {
+			return fmt.Errorf("invalid argument: %s", arg)
+		}
+		if flag.Value == "" {
+			if hasValue {
+				return fmt.Errorf("%s takes no value", arg)
+			}
+			p.args.flags[flag.Name] = append(p.args.flags[flag.Name], "")
+		} else if hasValue {
+			p.args.flags[flag.Name] = append(p.args.flags[flag.Name], value)
+		} else {
+			p.flag, p.flagArg = flag, arg
+		}
+		return nil
+	}
+	// Subcommands. IDs may be given before the verb (e.g. "worker 1 2 delete")
+	if !p.literal && len(p.cmd.Commands) > 0 {
+		leadingIDs := true
+		for _, positional := range p.args.Positional {
+			leadingIDs = leadingIDs && isInteger(positional)
+		}
+		if leadingIDs {
+			if sub := p.cmd.Command(arg); sub != nil {
+				p.cmd = sub
+				return nil
+			}
+			if len(p.args.Positional) == 0 && !isInteger(arg) && p.cmd.Default == "" {
+				return fmt.Errorf("invalid command: %s %s", p.cmd.Path(), arg)
+			}
+		}
+	}
+	p.args.Positional = append(p.args.Positional, arg)
+	return nil
+}
+
+// done completes the parsing and returns the selected command
+func (p *parser) done() (*Command, *Args, error) {
+	if p.flag != nil {
+		return p.cmd, &p.args, fmt.Errorf("missing argument: %s", p.flagArg)
+	}
+	for p.cmd.Run == nil && p.cmd.Default != "" {
+		p.cmd = p.cmd.Command(p.cmd.Default)
+	}
+	if p.cmd.Run == nil {
+		return p.cmd, &p.args, fmt.Errorf("missing command: %s", p.cmd.Path())
+	}
+	return p.cmd, &p.args, nil
+}
+
+/* Parse the command line arguments and return the selected command */
+func parseCommand(root *Command, args []string) (*Command, *Args, error) {
+	p := newParser(root)
+	for _, arg := range args {
+		if err := p.next(arg); err != nil {
+			return p.cmd, &p.args, err
+		}
+	}
+	return p.done()
+}
+
+// flagName returns the flag as shown in the help text, e.g. "-r, --remote INSTANCE"
+func (f *Flag) flagName() string {
+	names := make([]string, 0)
+	if f.Short != "" {
+		names = append(names, "-"+f.Short)
+	}
+	names = append(names, "--"+f.Name)
+	name := strings.Join(names, ", ")
+	if f.Value != "" {
+		name += " " + f.Value
+	}
+	return name
+}
+
+func printHelpLine(name string, help string) {
+	fmt.Printf("  %-46s %s\n", name, help)
+}
+
+/* Print the help text of the command */
+func (c *Command) Usage() {
+	program := filepath.Base(os.Args[0])
+	path := strings.TrimPrefix(c.Path(), c.root().Name)
+	line := program + path
+	if c.parent == nil {
+		line += " [OPTIONS] COMMAND"
+	} else {
+		line += " [OPTIONS]"
+		if len(c.Commands) > 0 {
+			line += " COMMAND"
+		}
+		if c.Args != "" {
+			line += " " + c.Args
+		}
+	}
+	fmt.Printf("Usage: %s\n", line)
+	if c.Help != "" {
+		fmt.Printf("\n%s\n", c.Help)
+	}
+	if len(c.Aliases) > 0 {
+		fmt.Printf("\nAliases: %s\n", strings.Join(c.Aliases, ", "))
+	}
+	if len(c.Commands) > 0 {
+		fmt.Println("\nCOMMANDS")
+		for _, sub := range c.Commands {
+			if sub.Hidden {
+				continue
+			}
+			name := sub.Name
+			if sub.Args != "" {
+				name += " " + sub.Args
+			}
+			help := strings.Split(sub.Help, "\n")[0]
+			if sub.Name == c.Default {
+				help += " (default)"
+			}
+			printHelpLine(name, help)
+		}
+	}
+	for cmd := c; cmd != nil; cmd = cmd.parent {
+		if len(cmd.Flags) == 0 {
+			continue
+		}
+		if cmd == c {
+			fmt.Println("\nOPTIONS")
+		} else if cmd.parent == nil {
+			fmt.Println("\nGLOBAL OPTIONS")
+		} else {
+			fmt.Printf("\nOPTIONS OF %s\n", strings.ToUpper(strings.TrimSpace(strings.TrimPrefix(cmd.Path(), c.root().Name))))
+		}
+		for _, flag := range cmd.Flags {
+			printHelpLine(flag.flagName(), flag.Help)
+		}
+	}
+	if c.Epilog != "" {
+		fmt.Printf("\n%s\n", c.Epilog)
+	}
+	if c.parent == nil {
+		fmt.Printf("\nRun '%s help COMMAND' for the help of a command\n", program)
+	}
+}
+
+func (c *Command) root() *Command {
+	if c.parent == nil {
+		return c
+	}
+	return c.parent.root()
+}
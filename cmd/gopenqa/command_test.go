@@ -0,0 +1,137 @@
+package main
+
+import (
+	"testing"
+
+	"gotest.tools/assert"
+)
+
+func nop(args *Args) error { return nil }
+
+// Command tree for the parser tests, independent of the commands of the CLI
+func testCommands() *Command {
+	return (&Command{Name: "tool", Flags: []Flag{
+		{Name: "remote", Short: "r", Aliases: []string{"openqa"}, Value: "INSTANCE"},
+		{Name: "verbose", Short: "v"},
+	}, Commands: []*Command{
+		{Name: "jobs", Default: "list", Commands: []*Command{
+			{Name: "list", Run: nop, Flags: []Flag{{Name: "state", Value: "STATES"}}},
+			{Name: "get", Aliases: []string{"show"}, Run: nop},
+		}},
+		{Name: "worker", Default: "get", Commands: []*Command{
+			{Name: "get", Run: nop},
+			{Name: "delete", Run: nop},
+		}},
+		{Name: "group", Commands: []*Command{
+			{Name: "get", Run: nop},
+		}},
+		{Name: "version", Run: nop},
+	}}).init()
+}
+
+func TestParseCommand(t *testing.T) {
+	tests := []struct {
+		name       string
+		args       []string
+		command    string
+		positional []string
+		flags      map[string][]string
+		err        string
+	}{
+		{"default subcommand", []string{"jobs"}, "tool jobs list", []string{}, map[string][]string{}, ""},
+		{"nested default subcommand", []string{"worker", "1"}, "tool worker get", []string{"1"}, map[string][]string{}, ""},
+		{"subcommand", []string{"jobs", "get", "1", "2"}, "tool jobs get", []string{"1", "2"}, map[string][]string{}, ""},
+		{"case insensitive alias", []string{"JOBS", "Show", "3"}, "tool jobs get", []string{"3"}, map[string][]string{}, ""},
+		{"IDs before the verb", []string{"worker", "1", "2", "delete"}, "tool worker delete", []string{"1", "2"}, map[string][]string{}, ""},
+		{"verb after other arguments", []string{"jobs", "get", "1", "list"}, "tool jobs get", []string{"1", "list"}, map[string][]string{}, ""},
+		{"flags of parents", []string{"jobs", "-r", "o3", "--verbose", "list", "--state=done"}, "tool jobs list", []string{}, map[string][]string{"remote": {"o3"}, "verbose": {""}, "state": {"done"}}, ""},
+		{"repeated flags", []string{"--openqa", "osd", "jobs", "list", "--state", "done", "--state=failed", "-r", "o3"}, "tool jobs list", []string{}, map[string][]string{"remote": {"osd", "o3"}, "state": {"done", "failed"}}, ""},
+		{"literal arguments", []string{"jobs", "get", "--", "--state", "-v", "list"}, "tool jobs get", []string{"--state", "-v", "list"}, map[string][]string{}, ""},
+		{"flag value looking like a flag", []string{"-r", "--", "version"}, "tool version", []string{}, map[string][]string{"remote": {"--"}}, ""},
+		{"empty flag value", []string{"--remote=", "version"}, "tool version", []string{}, map[string][]string{"remote": {""}}, ""},
+		{"single dash", []string{"version", "-"}, "tool version", []string{"-"}, map[string][]string{}, ""},
+		{"missing value", []string{"jobs", "list", "--state"}, "", nil, nil, "missing argument: --state"},
+		// Flags belong to the verb, so that the default subcommand cannot be mistaken for another one
+		{"flag of the default subcommand", []string{"jobs", "--state", "done"}, "", nil, nil, "invalid argument: --state"},
+		{"missing short value", []string{"version", "-r"}, "", nil, nil, "missing argument: -r"},
+		{"value for boolean flag", []string{"--verbose=1", "version"}, "", nil, nil, "--verbose=1 takes no value"},
+		{"flag of subcommand on parent", []string{"--state", "done", "jobs"}, "", nil, nil, "invalid argument: --state"},
+		{"short name as long flag", []string{"--r", "o3"}, "", nil, nil, "invalid argument: --r"},
+		{"invalid command", []string{"nonexisting"}, "", nil, nil, "invalid command: tool nonexisting"},
+		{"invalid verb", []string{"group", "delete"}, "", nil, nil, "invalid command: tool group delete"},
+		{"missing command", []string{"group"}, "", nil, nil, "missing command: tool group"},
+		{"no arguments", []string{}, "", nil, nil, "missing command: tool"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cmd, args, err := parseCommand(testCommands(), test.args)
+			if test.err != "" {
+				assert.Error(t, err, test.err)
+				return
+			}
+			assert.NilError(t, err)
+			assert.Equal(t, cmd.Path(), test.command)
+			assert.DeepEqual(t, args.Positional, test.positional)
+			assert.DeepEqual(t, args.flags, test.flags)
+		})
+	}
+}
+
+func TestArgs(t *testing.T) {
+	_, args, err := parseCommand(testCommands(), []string{"jobs", "list", "--state", "done,failed", "--state", "incomplete"})
+	assert.NilError(t, err)
+	assert.Assert(t, args.Has("state"))
+	assert.Assert(t, !args.Has("remote"))
+	assert.Equal(t, args.Value("state"), "incomplete")
+	assert.Equal(t, args.Value("remote"), "")
+	assert.DeepEqual(t, args.Values("state"), []string{"done", "failed", "incomplete"})
+	assert.DeepEqual(t, args.Values("remote"), []string{})
+}
+
+func TestComplete(t *testing.T) {
+	root = commands()
+	defer func() { root = nil }()
+	filename := writeTestConfig(t)
+	tests := []struct {
+		name       string
+		words      []string
+		candidates []string
+	}{
+		{"subcommands", []string{"jobg"}, []string{"jobgroups", "jobgroup"}},
+		{"subcommands in declaration order", []string{"jobs"}, []string{"jobs", "jobstate"}},
+		{"subcommands of a command", []string{"jobs", ""}, []string{"get", "search"}},
+		{"hidden commands", []string{"__"}, []string{}},
+		{"no subcommands after arguments", []string{"jobs", "1", "s"}, []string{}},
+		{"flags", []string{"--ou"}, []string{"--output"}},
+		{"flags of the command and its parents", []string{"jobs", "search", "--f"}, []string{"--flavor", "--follow-clones"}},
+		{"flags of other commands", []string{"--fl"}, []string{}},
+		{"no flags after --", []string{"jobs", "get", "--", "--"}, []string{}},
+		{"flag values", []string{"-o", ""}, outputFormats},
+		{"flag values with prefix", []string{"jobs", "--output", "c"}, []string{"csv"}},
+		{"positional arguments", []string{"completion", "z"}, []string{"zsh"}},
+		{"files", []string{"backup", "ba"}, []string{completeFiles}},
+		{"files as flag values", []string{"--config", "conf"}, []string{completeFiles}},
+		{"commands", []string{"help", "jobs", "s"}, []string{"search"}},
+		{"commands of unknown commands", []string{"help", "nonexisting", ""}, []string{}},
+		{"profiles", []string{"--config", filename, "--profile", ""}, []string{"env", "o3", "staging"}},
+		{"remotes", []string{"--config", filename, "-r", "o"}, []string{"o3", "ooo", "osd"}},
+		{"remotes of the configuration file", []string{"--config", filename, "--remote", "st"}, []string{"staging"}},
+		{"no words", []string{}, nil},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cf.ApplyDefaults()
+			defer cf.ApplyDefaults()
+			candidates := complete(test.words)
+			if test.candidates == nil {
+				// All visible commands
+				assert.Assert(t, len(candidates) > 10)
+				for _, candidate := range candidates {
+					assert.Assert(t, candidate != "__complete")
+				}
+				return
+			}
+			assert.DeepEqual(t, candidates, test.candidates)
+		})
+	}
+}
@@ -3,39 +3,25 @@ package main
 import (
 	"fmt"
 	"strconv"
-	"strings"
 )
 
-func runComments(args []string) error {
-	method := "GET"
-
-	if len(args) < 1 {
+func getComments(args *Args) error {
+	if len(args.Positional) < 1 {
 		return fmt.Errorf("not enough arguments")
+	} else if len(args.Positional) > 1 {
+		return fmt.Errorf("too many arguments")
 	}
-	// get method
-	var id int64
-	if len(args) == 1 {
-		method = "GET"
-		id, _ = strconv.ParseInt(args[0], 10, 64)
-	} else {
-		method = strings.ToUpper(strings.TrimSpace(args[0]))
-		id, _ = strconv.ParseInt(args[1], 10, 64)
-		args = args[2:]
-	}
+	id, _ := strconv.ParseInt(args.Positional[0], 10, 64)
 	if id <= 0 {
 		return fmt.Errorf("invalid ID")
 	}
 
-	if method == "GET" {
-		comments, err := instance.GetComments(id)
-		if err != nil {
-			return err
-		}
-		if err := printJson(comments); err != nil {
-			return err
-		}
-		return nil
-	} else {
-		return fmt.Errorf("Method %s is not (yet) supported", method)
+	comments, err := instance.GetComments(id)
+	if err != nil {
+		return err
+	}
+	if err := printData(comments); err != nil {
+		return err
 	}
+	return nil
 }
@@ -0,0 +1,189 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Marker printed by __complete to let the shell complete file names
+const completeFiles = ":files"
+
+const bashCompletion = `# bash completion for %[1]s
+_%[2]s() {
+    local cur="${COMP_WORDS[COMP_CWORD]}"
+    local IFS=$'\n'
+    local candidates=($(%[1]s __complete -- "${COMP_WORDS[@]:1:COMP_CWORD}" 2>/dev/null))
+    if [ "${candidates[0]}" = "%[3]s" ]; then
+        COMPREPLY=($(compgen -f -- "$cur"))
+        return
+    fi
+    COMPREPLY=($(compgen -W "${candidates[*]}" -- "$cur"))
+    # Job group names may contain spaces
+    if [ ${#COMPREPLY[@]} -gt 0 ]; then
+        COMPREPLY=($(printf '%%q\n' "${COMPREPLY[@]}"))
+    fi
+}
+complete -F _%[2]s %[1]s
+`
+
+const zshCompletion = `#compdef %[1]s
+_%[2]s() {
+    local -a candidates
+    candidates=("${(@f)$(%[1]s __complete -- "${(@)words[2,CURRENT]}" 2>/dev/null)}")
+    if [[ "${candidates[1]}" == "%[3]s" ]]; then
+        _files
+        return
+    fi
+    compadd -a candidates
+}
+compdef _%[2]s %[1]s
+`
+
+const fishCompletion = `# fish completion for %[1]s
+function __%[2]s_complete
+    set -l tokens (commandline -opc) (commandline -ct)
+    set -l candidates (%[1]s __complete -- $tokens[2..-1] 2>/dev/null)
+    if test "$candidates[1]" = "%[3]s"
+        __fish_complete_path (commandline -ct)
+        return
+    end
+    printf '%%s\n' $candidates
+end
+complete -c %[1]s -f -a '(__%[2]s_complete)'
+`
+
+/* Print the completion script for the given shell */
+func runCompletion(args *Args) error {
+	if len(args.Positional) != 1 {
+		return fmt.Errorf("usage: completion bash|zsh|fish")
+	}
+	program := filepath.Base(os.Args[0])
+	function := strings.NewReplacer("-", "_", ".", "_").Replace(program)
+	switch args.Positional[0] {
+	case "bash":
+		fmt.Printf(bashCompletion, program, function, completeFiles)
+	case "zsh":
+		fmt.Printf(zshCompletion, program, function, completeFiles)
+	case "fish":
+		fmt.Printf(fishCompletion, program, function, completeFiles)
+	default:
+		return fmt.Errorf("unsupported shell: %s", args.Positional[0])
+	}
+	return nil
+}
+
+/* Hidden command called by the completion scripts with the words of the command line. The last word is the one to complete */
+func runComplete(args *Args) error {
+	for _, candidate := range complete(args.Positional) {
+		fmt.Println(candidate)
+	}
+	return nil
+}
+
+// complete returns the candidates for the last of the given words
+func complete(words []string) []string {
+	if len(words) == 0 {
+		words = []string{""}
+	}
+	current := words[len(words)-1]
+	p := newParser(root)
+	for _, word := range words[:len(words)-1] {
+		// Errors are expected for incomplete command lines
+		p.next(word)
+	}
+
+	candidates := make([]string, 0)
+	if p.flag != nil {
+		candidates = completeKind(p.flag.Complete, &p.args)
+	} else if strings.HasPrefix(current, "-") && !p.literal {
+		for cmd := p.cmd; cmd != nil; cmd = cmd.parent {
+			for _, flag := range cmd.Flags {
+				candidates = append(candidates, "--"+flag.Name)
+			}
+		}
+	} else {
+		cmd := p.cmd
+		if len(cmd.Commands) > 0 && len(p.args.Positional) == 0 {
+			for _, sub := range cmd.Commands {
+				if !sub.Hidden {
+					candidates = append(candidates, sub.Name)
+				}
+			}
+		}
+		for cmd.Run == nil && cmd.Default != "" {
+			cmd = cmd.Command(cmd.Default)
+		}
+		candidates = append(candidates, completeKind(cmd.Complete, &p.args)...)
+	}
+
+	ret := make([]string, 0)
+	for _, candidate := range candidates {
+		if candidate == completeFiles || strings.HasPrefix(candidate, current) {
+			ret = append(ret, candidate)
+		}
+	}
+	return ret
+}
+
+// completeKind returns the candidates for the given kind of value. Dynamic values are fetched from the instance selected by the already given arguments
+func completeKind(kind string, args *Args) []string {
+	candidates := make([]string, 0)
+	switch kind {
+	case "files":
+		candidates = append(candidates, completeFiles)
+	case "outputs":
+		candidates = append(candidates, outputFormats...)
+	case "shells":
+		candidates = append(candidates, "bash", "zsh", "fish")
+	case "commands":
+		cmd := root
+		for _, name := range args.Positional {
+			if cmd = cmd.Command(name); cmd == nil {
+				return candidates
+			}
+		}
+		for _, sub := range cmd.Commands {
+			if !sub.Hidden {
+				candidates = append(candidates, sub.Name)
+			}
+		}
+	case "remotes":
+		setup(args)
+		for alias := range cf.Aliases {
+			candidates = append(candidates, alias)
+		}
+		sort.Strings(candidates)
+	case "profiles":
+		filename := args.Value("config")
+		if filename == "" {
+			filename = defaultConfigFile()
+		}
+		file, _ := ReadConfigFile(filename, false)
+		for name := range file.Profiles {
+			candidates = append(candidates, name)
+		}
+		sort.Strings(candidates)
+	case "jobgroups":
+		if setup(args) != nil {
+			return candidates
+		}
+		if groups, err := instance.GetJobGroups(); err == nil {
+			for _, group := range groups {
+				candidates = append(candidates, group.Name)
+			}
+		}
+	case "machines":
+		if setup(args) != nil {
+			return candidates
+		}
+		if machines, err := instance.GetMachines(); err == nil {
+			for _, machine := range machines {
+				candidates = append(candidates, machine.Name)
+			}
+		}
+	}
+	return candidates
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"gotest.tools/assert"
+)
+
+const testConfig = `profile: o3
+aliases:
+  o3: https://o3.example.com
+  staging: https://openqa.example.com
+profiles:
+  o3:
+    remote: o3
+    apikey: PROFILEKEY
+    apisecret: PROFILESECRET
+    output: table
+  staging:
+    remote: staging
+    credentials: %s
+    amqp: amqps://rabbit.example.com
+    verbose: true
+  env:
+    remote: osd
+    credentials: env
+`
+
+const testClientConf = `[openqa.suse.de]
+key = OSDKEY
+secret = OSDSECRET
+
+[openqa.example.com]
+key = STAGINGKEY
+secret = STAGINGSECRET
+`
+
+// writeTestConfig writes the configuration file and the client.conf it refers to and returns the configuration file
+func writeTestConfig(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	clientConf := filepath.Join(dir, "client.conf")
+	assert.NilError(t, os.WriteFile(clientConf, []byte(testClientConf), 0644))
+	filename := filepath.Join(dir, "config.yaml")
+	assert.NilError(t, os.WriteFile(filename, []byte(fmt.Sprintf(testConfig, clientConf)), 0644))
+	return filename
+}
+
+func TestConfigPrecedence(t *testing.T) {
+	filename := writeTestConfig(t)
+	empty := filepath.Join(filepath.Dir(filename), "empty.yaml")
+	assert.NilError(t, os.WriteFile(empty, []byte{}, 0644))
+	tests := []struct {
+		name    string
+		args    []string
+		env     string // GOPENQA_APIKEY, the secret is derived from it
+		remote  string
+		key     string
+		secret  string
+		output  string
+		verbose bool
+	}{
+		{"defaults", []string{"--config", empty}, "", "https://openqa.opensuse.org", "", "", "", false},
+		{"default profile", []string{"--config", filename}, "", "https://o3.example.com", "PROFILEKEY", "PROFILESECRET", "table", false},
+		{"env over profile", []string{"--config", filename}, "ENVKEY", "https://o3.example.com", "ENVKEY", "ENVKEYSECRET", "table", false},
+		{"env over defaults", []string{"--config", empty}, "ENVKEY", "https://openqa.opensuse.org", "ENVKEY", "ENVKEYSECRET", "", false},
+		{"flags over env", []string{"--config", filename, "-k", "FLAGKEY", "--apisecret=FLAGSECRET", "-o", "CSV"}, "ENVKEY", "https://o3.example.com", "FLAGKEY", "FLAGSECRET", "csv", false},
+		{"flags over profile", []string{"--config", filename, "-r", "staging", "-k", "FLAGKEY", "-s", "FLAGSECRET", "-v"}, "", "https://openqa.example.com", "FLAGKEY", "FLAGSECRET", "table", true},
+		{"selected profile", []string{"--config", filename, "--profile", "staging"}, "", "https://openqa.example.com", "STAGINGKEY", "STAGINGSECRET", "", true},
+		{"env over credentials file", []string{"--config", filename, "--profile", "staging"}, "ENVKEY", "https://openqa.example.com", "ENVKEY", "ENVKEYSECRET", "", true},
+		// Credentials are looked up for the remote given on the command line
+		{"remote flag and credentials file", []string{"--config", filename, "--profile", "staging", "--remote", "osd"}, "", "http://openqa.suse.de", "OSDKEY", "OSDSECRET", "", true},
+		{"env credentials", []string{"--config", filename, "--profile", "env"}, "ENVKEY", "http://openqa.suse.de", "ENVKEY", "ENVKEYSECRET", "", false},
+		{"env credentials without env", []string{"--config", filename, "--profile", "env"}, "", "http://openqa.suse.de", "", "", "", false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Setenv("GOPENQA_APIKEY", test.env)
+			t.Setenv("GOPENQA_APISECRET", "")
+			if test.env != "" {
+				t.Setenv("GOPENQA_APISECRET", test.env+"SECRET")
+			}
+			cf.ApplyDefaults()
+			defer cf.ApplyDefaults()
+			_, args, err := parseCommand(commands(), append(test.args, "machines"))
+			assert.NilError(t, err)
+			assert.NilError(t, setup(args))
+			assert.Equal(t, cf.Remote, test.remote)
+			assert.Equal(t, cf.ApiKey, test.key)
+			assert.Equal(t, cf.ApiSecret, test.secret)
+			assert.Equal(t, cf.Output, test.output)
+			assert.Equal(t, cf.Verbose, test.verbose)
+			assert.Equal(t, instance.URL, test.remote)
+		})
+	}
+}
+
+func TestConfigErrors(t *testing.T) {
+	filename := writeTestConfig(t)
+	tests := []struct {
+		name string
+		args []string
+		err  string
+	}{
+		{"unknown profile", []string{"--config", filename, "--profile", "nonexisting"}, "profile not found: nonexisting"},
+		{"missing config file", []string{"--config", filename + ".missing"}, "no such file"},
+		{"invalid output", []string{"--config", filename, "-o", "xml"}, "invalid output format: xml"},
+		{"missing credentials", []string{"--config", filename, "--profile", "staging", "-r", "https://openqa.opensuse.org"}, "no credentials for openqa.opensuse.org"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Setenv("GOPENQA_APIKEY", "")
+			cf.ApplyDefaults()
+			defer cf.ApplyDefaults()
+			_, args, err := parseCommand(commands(), append(test.args, "machines"))
+			assert.NilError(t, err)
+			assert.ErrorContains(t, setup(args), test.err)
+		})
+	}
+}
+
+func TestMagicRemote(t *testing.T) {
+	var config Config
+	config.ApplyDefaults()
+	file, err := ReadConfigFile(writeTestConfig(t), true)
+	assert.NilError(t, err)
+	tests := []struct {
+		remote   string
+		expanded string
+		file     bool // With the aliases of the configuration file
+	}{
+		{"o3", "https://openqa.opensuse.org", false},
+		{"ooo", "https://openqa.opensuse.org", false},
+		{"osd", "http://openqa.suse.de", false},
+		{"staging", "staging", false},
+		{"", "https://openqa.opensuse.org", false},
+		{"https://openqa.example.com", "https://openqa.example.com", false},
+		{"http://o3", "http://o3", false},
+		{"localhost:9526", "localhost:9526", false},
+		// Aliases of the configuration file add to and replace the default aliases
+		{"o3", "https://o3.example.com", true},
+		{"staging", "https://openqa.example.com", true},
+		{"osd", "http://openqa.suse.de", true},
+	}
+	for _, test := range tests {
+		if test.file {
+			assert.NilError(t, config.ApplyConfigFile(ConfigFile{Aliases: file.Aliases}, ""))
+		}
+		assert.Equal(t, config.magicRemote(test.remote), test.expanded, test.remote)
+	}
+}
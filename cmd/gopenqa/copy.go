@@ -0,0 +1,68 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/os-autoinst/gopenqa"
+)
+
+/* Print the outcome of an import */
+func printImportReport(report gopenqa.ImportReport) {
+	if cf.Output != "" {
+		printData(report.Items)
+		return
+	}
+	for _, item := range report.Items {
+		if item.Action == gopenqa.ImportSkipped && !cf.Verbose {
+			continue
+		}
+		fmt.Printf("%-8s %-13s %s (%d -> %d)\n", item.Action, item.Entity, item.Name, item.SourceID, item.ID)
+	}
+	fmt.Printf("%d created, %d updated, %d skipped\n", report.Count(gopenqa.ImportCreated), report.Count(gopenqa.ImportUpdated), report.Count(gopenqa.ImportSkipped))
+}
+
+/* Parse a list of job group IDs */
+func parseGroupIDs(values []string) ([]int, error) {
+	ids := make([]int, 0)
+	for _, v := range values {
+		id, err := strconv.Atoi(v)
+		if err != nil {
+			return ids, fmt.Errorf("invalid group id: %s", v)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+/* Copy the test configuration from another instance into the current instance */
+func runCopy(args *Args) error {
+	var opts gopenqa.ImportOptions
+	if len(args.Positional) > 0 {
+		return fmt.Errorf("invalid argument: %s", args.Positional[0])
+	}
+	from := ""
+	if args.Has("from") {
+		from = cf.magicRemote(args.Value("from"))
+	}
+	opts.Update = args.Has("update")
+	if args.Has("group") {
+		ids, err := parseGroupIDs(args.Values("group"))
+		if err != nil {
+			return err
+		}
+		opts.JobGroups = ids
+	}
+	if from == "" {
+		return fmt.Errorf("missing source instance (--from)")
+	}
+	if from == instance.URL {
+		return fmt.Errorf("source and destination are the same instance")
+	}
+
+	src := gopenqa.CreateInstance(from)
+	src.SetVerbose(cf.Verbose)
+	report, err := gopenqa.CopyConfiguration(&src, &instance, opts)
+	printImportReport(report)
+	return err
+}
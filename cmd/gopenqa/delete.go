@@ -0,0 +1,108 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/os-autoinst/gopenqa"
+)
+
+// Flags of the bulk deletion commands. older-than is only added for entities that have an age
+func deleteFlags(age bool) []Flag {
+	flags := []Flag{
+		{Name: "name", Value: "REGEX", Help: "Only items with a name matching the regular expression"},
+		{Name: "ids", Value: "RANGE", Help: "Only items in the given ID range (e.g. 10-20, 10- or -20)"},
+		{Name: "unused", Help: "Only items that are not used by any job template"},
+	}
+	if age {
+		flags = append(flags, Flag{Name: "older-than", Value: "DURATION", Help: "Only job groups without jobs within the given duration (e.g. 90d)"})
+	}
+	return append(flags,
+		Flag{Name: "dry-run", Help: "Only show what would be deleted"},
+		Flag{Name: "continue-on-error", Help: "Continue with the remaining items if a deletion fails"},
+	)
+}
+
+// Parse an ID range like 10-20, 10- or -20. A single ID is a range as well
+func parseIDRange(value string) (int64, int64, error) {
+	lower, upper, found := strings.Cut(value, "-")
+	if !found {
+		upper = lower
+	}
+	if lower == "" && upper == "" {
+		// An empty range would silently select all items
+		return 0, 0, fmt.Errorf("invalid ID range: %s", value)
+	}
+	var first, last int64
+	var err error
+	if lower != "" {
+		if first, err = strconv.ParseInt(lower, 10, 64); err != nil || first <= 0 {
+			return 0, 0, fmt.Errorf("invalid ID range: %s", value)
+		}
+	}
+	if upper != "" {
+		if last, err = strconv.ParseInt(upper, 10, 64); err != nil || last <= 0 {
+			return 0, 0, fmt.Errorf("invalid ID range: %s", value)
+		}
+	}
+	if last > 0 && first > last {
+		return 0, 0, fmt.Errorf("invalid ID range: %s", value)
+	}
+	return first, last, nil
+}
+
+/* Parse the filter flags of a bulk deletion. Returns true if any filter is given */
+func parseDeleteFilter(args *Args) (gopenqa.DeleteFilter, bool, error) {
+	var filter gopenqa.DeleteFilter
+	var err error
+	if len(args.Positional) > 0 {
+		return filter, false, fmt.Errorf("invalid argument: %s", args.Positional[0])
+	}
+	filter.Name = args.Value("name")
+	if args.Has("ids") {
+		if filter.MinID, filter.MaxID, err = parseIDRange(args.Value("ids")); err != nil {
+			return filter, false, err
+		}
+	}
+	if args.Has("older-than") {
+		if filter.OlderThan, err = parseDuration(args.Value("older-than")); err != nil {
+			return filter, false, err
+		}
+	}
+	filter.Unused = args.Has("unused")
+	filtered := filter.Name != "" || filter.MinID > 0 || filter.MaxID > 0 || filter.OlderThan > 0 || filter.Unused
+	return filter, filtered, nil
+}
+
+/* Options of a bulk deletion of count items. Prints the outcome of each item */
+func deleteOptions(args *Args, entity string, count int) gopenqa.DeleteOptions {
+	options := gopenqa.DeleteOptions{DryRun: args.Has("dry-run"), ContinueOnError: args.Has("continue-on-error")}
+	i := 0
+	options.Progress = func(outcome gopenqa.DeleteOutcome) {
+		i++
+		if options.DryRun {
+			fmt.Printf("Would delete %s %s\n", entity, outcome.String())
+		} else if outcome.Deleted {
+			fmt.Printf("[%d/%d] Deleted %s %s\n", i, count, entity, outcome.String())
+		} else if outcome.Skipped {
+			fmt.Printf("[%d/%d] Skipped %s %s\n", i, count, entity, outcome.String())
+		} else {
+			fmt.Printf("[%d/%d] Failed to delete %s %s: %s\n", i, count, entity, outcome.String(), outcome.Err)
+		}
+	}
+	return options
+}
+
+/* Print the summary of a bulk deletion and return an error, if any deletion failed */
+func deleteSummary(report gopenqa.DeleteReport, entities string, dryRun bool) error {
+	if dryRun {
+		fmt.Printf("%d %s would be deleted\n", len(report), entities)
+		return nil
+	}
+	fmt.Printf("Deleted %d of %d %s, %d failed, %d skipped\n", report.Deleted(), len(report), entities, report.Failed(), report.Skipped())
+	if report.Failed() > 0 {
+		return fmt.Errorf("%d %s could not be deleted", report.Failed(), entities)
+	}
+	return nil
+}
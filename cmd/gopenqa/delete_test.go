@@ -0,0 +1,82 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/os-autoinst/gopenqa"
+	"gotest.tools/assert"
+)
+
+func TestParseIDRange(t *testing.T) {
+	tests := []struct {
+		value string
+		first int64
+		last  int64
+		err   bool
+	}{
+		{"10-20", 10, 20, false},
+		{"10-", 10, 0, false},
+		{"-20", 0, 20, false},
+		{"5", 5, 5, false},
+		{"7-7", 7, 7, false},
+		{"20-10", 0, 0, true},
+		{"0", 0, 0, true},
+		{"0-10", 0, 0, true},
+		{"-", 0, 0, true},
+		{"a-b", 0, 0, true},
+		{"10-20-30", 0, 0, true},
+		{"", 0, 0, true},
+	}
+	for _, test := range tests {
+		first, last, err := parseIDRange(test.value)
+		if test.err {
+			assert.Error(t, err, "invalid ID range: "+test.value)
+			continue
+		}
+		assert.NilError(t, err, test.value)
+		assert.Equal(t, first, test.first, test.value)
+		assert.Equal(t, last, test.last, test.value)
+	}
+}
+
+func TestParseDeleteFilter(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []string
+		filter   gopenqa.DeleteFilter
+		filtered bool
+		err      string
+	}{
+		{"no filters", []string{"jobgroups", "clear"}, gopenqa.DeleteFilter{}, false, ""},
+		{"name", []string{"jobgroups", "clear", "--name", "^SLE"}, gopenqa.DeleteFilter{Name: "^SLE"}, true, ""},
+		{"ID range", []string{"jobgroups", "clear", "--ids", "10-20"}, gopenqa.DeleteFilter{MinID: 10, MaxID: 20}, true, ""},
+		{"lower bound", []string{"jobgroups", "clear", "--ids=10-"}, gopenqa.DeleteFilter{MinID: 10}, true, ""},
+		{"upper bound", []string{"jobgroups", "clear", "--ids", "-20"}, gopenqa.DeleteFilter{MaxID: 20}, true, ""},
+		{"age and unused", []string{"jobgroups", "clear", "--older-than", "90d", "--unused", "--dry-run"}, gopenqa.DeleteFilter{OlderThan: 90 * 24 * time.Hour, Unused: true}, true, ""},
+		{"options are no filters", []string{"machines", "clear", "--dry-run", "--continue-on-error"}, gopenqa.DeleteFilter{}, false, ""},
+		{"reversed range", []string{"jobgroups", "clear", "--ids", "20-10"}, gopenqa.DeleteFilter{}, false, "invalid ID range: 20-10"},
+		{"invalid age", []string{"jobgroups", "clear", "--older-than", "soon"}, gopenqa.DeleteFilter{}, false, "invalid duration"},
+		// IDs must be given as range, so that a clear is never mistaken for a delete
+		{"stray positional argument", []string{"jobgroups", "clear", "5"}, gopenqa.DeleteFilter{}, false, "invalid argument: 5"},
+		{"stray positional argument with filters", []string{"machines", "clear", "--name", "uefi", "64bit"}, gopenqa.DeleteFilter{}, false, "invalid argument: 64bit"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, args, err := parseCommand(commands(), test.args)
+			assert.NilError(t, err)
+			filter, filtered, err := parseDeleteFilter(args)
+			if test.err != "" {
+				assert.ErrorContains(t, err, test.err)
+				return
+			}
+			assert.NilError(t, err)
+			assert.Equal(t, filter, test.filter)
+			assert.Equal(t, filtered, test.filtered)
+		})
+	}
+
+	// The age filter is only available for job groups
+	_, _, err := parseCommand(commands(), []string{"machines", "clear", "--older-than", "90d"})
+	assert.Error(t, err, "invalid argument: --older-than")
+}
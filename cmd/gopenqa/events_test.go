@@ -0,0 +1,68 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/os-autoinst/gopenqa"
+	"gotest.tools/assert"
+)
+
+func TestFormatEvent(t *testing.T) {
+	tests := []struct {
+		name  string
+		event gopenqa.Event
+		text  string
+	}{
+		{"job", gopenqa.Event{Type: gopenqa.EventJobDone, Job: &gopenqa.JobStatus{ID: 42, Test: "minimal", Machine: "64bit", Build: "20240101", Result: "passed"}}, " 42 minimal@64bit build 20240101: passed"},
+		{"comment", gopenqa.Event{Type: gopenqa.EventCommentCreate, Comment: &gopenqa.CommentMQ{JobID: 42, User: "phoenix", Text: "poo#42\nfixed"}}, " job 42 phoenix: poo#42 fixed"},
+		{"long comment", gopenqa.Event{Type: gopenqa.EventCommentCreate, Comment: &gopenqa.CommentMQ{GroupID: 7, User: "phoenix", Text: strings.Repeat("a", 100)}}, " group 7 phoenix: " + strings.Repeat("a", 77) + "..."},
+		// Multi-byte characters are not cut in half
+		{"long unicode comment", gopenqa.Event{Type: gopenqa.EventCommentCreate, Comment: &gopenqa.CommentMQ{GroupID: 7, User: "phoenix", Text: strings.Repeat("ü", 100)}}, " group 7 phoenix: " + strings.Repeat("ü", 77) + "..."},
+		{"unicode comment", gopenqa.Event{Type: gopenqa.EventCommentCreate, Comment: &gopenqa.CommentMQ{GroupID: 7, User: "phoenix", Text: strings.Repeat("ü", 80)}}, " group 7 phoenix: " + strings.Repeat("ü", 80)},
+		{"job group", gopenqa.Event{Type: gopenqa.EventJobGroupDelete, JobGroup: &gopenqa.JobGroupEvent{ID: 7, Name: "openSUSE Tumbleweed"}}, " 7 openSUSE Tumbleweed"},
+		{"unknown", gopenqa.Event{Type: "user.login", Raw: []byte(`{"user":"phoenix"}`)}, ` {"user":"phoenix"}`},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			line := formatEvent(test.event)
+			assert.Assert(t, utf8.ValidString(line), line)
+			assert.Assert(t, strings.HasSuffix(line, test.event.Type+strings.Repeat(" ", 18-len(test.event.Type))+test.text), line)
+		})
+	}
+}
+
+func TestEventBroker(t *testing.T) {
+	filename := writeTestConfig(t)
+	empty := filepath.Join(filepath.Dir(filename), "empty.yaml")
+	assert.NilError(t, os.WriteFile(empty, []byte{}, 0644))
+	tests := []struct {
+		name   string
+		args   []string
+		broker string
+		bound  bool // Events are linked to the selected instance
+	}{
+		{"default", []string{"--config", empty, "events"}, DEFAULT_AMQP, true},
+		{"default broker of another instance", []string{"--config", empty, "-r", "osd", "events"}, DEFAULT_AMQP, false},
+		{"profile without broker", []string{"--config", filename, "--profile", "o3", "events"}, DEFAULT_AMQP, false},
+		{"profile", []string{"--config", filename, "--profile", "staging", "events"}, "amqps://rabbit.example.com", true},
+		{"profile broker of another instance", []string{"--config", filename, "--profile", "staging", "-r", "osd", "events"}, "amqps://rabbit.example.com", false},
+		{"broker flag", []string{"--config", filename, "--profile", "staging", "-r", "osd", "events", "--amqp", "amqps://rabbit.suse.de"}, "amqps://rabbit.suse.de", true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Setenv("GOPENQA_APIKEY", "")
+			cf.ApplyDefaults()
+			defer cf.ApplyDefaults()
+			_, args, err := parseCommand(commands(), test.args)
+			assert.NilError(t, err)
+			assert.NilError(t, setup(args))
+			broker, remote := eventBroker(args)
+			assert.Equal(t, broker, test.broker)
+			assert.Equal(t, remote == cf.Remote, test.bound, remote)
+		})
+	}
+}
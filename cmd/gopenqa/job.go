@@ -2,18 +2,22 @@ package main
 
 import (
 	"fmt"
+	"os"
 	"strconv"
+
+	"github.com/os-autoinst/gopenqa"
 )
 
-func runJobState(args []string) error {
+func runJobState(args *Args) error {
+	positional := args.Positional
 	var id int64
-	if len(args) < 1 {
+	if len(positional) < 1 {
 		return fmt.Errorf("missing argument: job")
-	} else if len(args) > 1 {
+	} else if len(positional) > 1 {
 		return fmt.Errorf("too many arguments")
 	}
 
-	id, _ = strconv.ParseInt(args[0], 10, 64)
+	id, _ = strconv.ParseInt(positional[0], 10, 64)
 	if id <= 0 {
 		return fmt.Errorf("invalid ID")
 	}
@@ -22,6 +26,9 @@ func runJobState(args []string) error {
 	if err != nil {
 		return err
 	}
+	if cf.Output != "" {
+		return printData(state)
+	}
 	if state.BlockedBy > 0 {
 		fmt.Printf("Blocked by %d\n", state.BlockedBy)
 	}
@@ -33,16 +40,16 @@ func runJobState(args []string) error {
 	return nil
 }
 
-func runJob(args []string) error {
-
+func runJob(args *Args) error {
+	positional := args.Positional
 	var id int64
-	if len(args) < 1 {
+	if len(positional) < 1 {
 		return fmt.Errorf("missing argument: job")
-	} else if len(args) > 1 {
+	} else if len(positional) > 1 {
 		return fmt.Errorf("too many arguments")
 	}
 
-	id, _ = strconv.ParseInt(args[0], 10, 64)
+	id, _ = strconv.ParseInt(positional[0], 10, 64)
 	if id <= 0 {
 		return fmt.Errorf("invalid ID")
 	}
@@ -51,17 +58,21 @@ func runJob(args []string) error {
 	if err != nil {
 		return err
 	}
+	if cf.Output != "" {
+		return printData(job)
+	}
 	fmt.Println(job.String())
 	return nil
 }
 
-func runJobs(args []string) error {
-	if len(args) < 1 {
+func runJobs(args *Args) error {
+	positional := args.Positional
+	if len(positional) < 1 {
 		return fmt.Errorf("missing argument: jobs")
 	}
 	ids := make([]int64, 0)
 
-	for _, arg := range args {
+	for _, arg := range positional {
 		id, err := strconv.ParseInt(arg, 10, 64)
 		if id <= 0 || err != nil {
 			return fmt.Errorf("invalid ID")
@@ -69,12 +80,71 @@ func runJobs(args []string) error {
 		ids = append(ids, id)
 	}
 
-	jobs, err := instance.GetJobsFollow(ids)
+	// Print all jobs that could be fetched and report the others
+	jobs := make([]gopenqa.Job, 0)
+	failed := 0
+	for _, response := range instance.GetJobResponses(ids, true) {
+		if response.Err != nil {
+			fmt.Fprintf(os.Stderr, "%d: %s\n", response.ID, response.Err)
+			failed++
+		} else {
+			jobs = append(jobs, response.Job)
+		}
+	}
+	if cf.Output != "" {
+		if err := printData(jobs); err != nil {
+			return err
+		}
+	} else {
+		for _, job := range jobs {
+			fmt.Printf("%s\n", job.String())
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d jobs could not be fetched", failed, len(ids))
+	}
+	return nil
+}
+
+/* Search jobs and print them as table with links */
+func searchJobs(args *Args) error {
+	if len(args.Positional) > 0 {
+		return fmt.Errorf("invalid argument: %s", args.Positional[0])
+	}
+	query := gopenqa.JobQuery{
+		Distri:       args.Value("distri"),
+		Version:      args.Value("version"),
+		Flavor:       args.Value("flavor"),
+		Arch:         args.Value("arch"),
+		Build:        args.Value("build"),
+		Test:         args.Value("test"),
+		State:        args.Values("state"),
+		Result:       args.Values("result"),
+		Latest:       args.Has("latest"),
+		FollowClones: args.Has("follow-clones"),
+	}
+	if args.Has("group") {
+		ids, err := resolveJobGroups([]string{args.Value("group")})
+		if err != nil {
+			return err
+		}
+		query.GroupID = ids[0]
+	}
+
+	jobs, err := instance.SearchJobs(query)
 	if err != nil {
 		return err
 	}
+	if cf.Output != "" {
+		return printData(jobs)
+	}
+	if len(jobs) == 0 {
+		fmt.Println("No jobs found")
+		return nil
+	}
+	rows := make([][]string, 0)
 	for _, job := range jobs {
-		fmt.Printf("%s\n", job.String())
+		rows = append(rows, []string{fmt.Sprintf("%d", job.ID), job.Test, job.Settings.Build, job.Settings.Arch, job.Settings.Machine, job.JobState(), job.Link})
 	}
-	return nil
+	return printTable([]string{"id", "test", "build", "arch", "machine", "result", "link"}, rows)
 }
@@ -5,7 +5,6 @@ import (
 	"fmt"
 	"io"
 	"os"
-	"strings"
 
 	"github.com/os-autoinst/gopenqa"
 )
@@ -51,8 +50,33 @@ func readJobGroups(filename string) ([]gopenqa.JobGroup, error) {
 	return jobgroups, fmt.Errorf("invalid input format")
 }
 
-func postJobGroups(args []string) error {
-	files := args
+/* Resolve job group IDs or names to IDs */
+func resolveJobGroups(values []string) ([]int, error) {
+	ids, names := extractIntegers(values)
+	if len(names) == 0 {
+		return ids, nil
+	}
+	jobgroups, err := instance.GetJobGroups()
+	if err != nil {
+		return ids, err
+	}
+	for _, name := range names {
+		found := false
+		for _, jobgroup := range jobgroups {
+			if jobgroup.Name == name {
+				ids = append(ids, jobgroup.ID)
+				found = true
+			}
+		}
+		if !found {
+			return ids, fmt.Errorf("job group not found: %s", name)
+		}
+	}
+	return ids, nil
+}
+
+func postJobGroups(args *Args) error {
+	files := args.Positional
 	if len(files) == 0 {
 		files = append(files, "")
 	}
@@ -74,8 +98,8 @@ func postJobGroups(args []string) error {
 	return nil
 }
 
-func postParentJobGroups(args []string) error {
-	files := args
+func postParentJobGroups(args *Args) error {
+	files := args.Positional
 	if len(files) == 0 {
 		files = append(files, "")
 	}
@@ -97,156 +121,118 @@ func postParentJobGroups(args []string) error {
 	return nil
 }
 
-func runJobGroups(args []string) error {
-	method := "GET"
-
-	if len(args) > 0 {
-		// get method
-		method = args[0]
-		args = args[1:]
+func listJobGroups(args *Args) error {
+	if len(args.Positional) > 0 {
+		return fmt.Errorf("too many arguments")
 	}
+	if jobgroups, err := instance.GetJobGroups(); err != nil {
+		return err
+	} else {
+		return printData(jobgroups)
+	}
+}
 
-	method = strings.ToUpper(strings.TrimSpace(method))
-	if method == "GET" {
-		if jobgroups, err := instance.GetJobGroups(); err != nil {
-			return err
+func clearJobGroups(args *Args) error {
+	filter, filtered, err := parseDeleteFilter(args)
+	if err != nil {
+		return err
+	}
+	jobgroups, err := instance.FilterJobGroups(filter)
+	if err != nil {
+		return err
+	}
+	if len(jobgroups) == 0 {
+		fmt.Println("No job groups to delete")
+		return nil
+	}
+	dryRun := args.Has("dry-run")
+	if !dryRun && !cf.NoPrompt {
+		if filtered {
+			for _, jobgroup := range jobgroups {
+				fmt.Printf("  %d %s\n", jobgroup.ID, jobgroup.Name)
+			}
+			fmt.Printf("Are you sure you want to delete %d job groups and their jobs? THERE WILL BE NO UNDO.\n", len(jobgroups))
 		} else {
-			return printJson(jobgroups)
-		}
-	} else if method == "POST" {
-		return postJobGroups(args)
-	} else if method == "CLEAR" {
-		if !cf.NoPrompt {
 			fmt.Println("DANGER ZONE !!")
 			fmt.Println("Are you sure you want to delete ALL job groups? THERE WILL BE NO UNDO, if you are hesitant then stop NOW.")
 			fmt.Println("Deleting job groups also means to delete all attached jobs!")
-			if prompt("Type uppercase 'yes' to continue: ") != "YES" {
-				return fmt.Errorf("cancelled")
-			}
 		}
-
-		if jobgroups, err := instance.GetJobGroups(); err != nil {
-			return err
-		} else {
-			fmt.Printf("Delete %d job groups and their jobs ... \n", len(jobgroups))
-			for i, jobgroup := range jobgroups {
-				id := jobgroup.ID
-				if err := instance.DeleteJobTemplate(id); err != nil {
-					return err
-				}
-				if err := instance.DeleteJobGroupJobs(id); err != nil {
-					return err
-				}
-				if err := instance.DeleteJobGroup(id); err != nil {
-					return err
-				}
-
-				fmt.Printf("[%d/%d] Deleted job group %d %s\n", i, len(jobgroups), jobgroup.ID, jobgroup.Name)
-
-			}
+		if prompt("Type uppercase 'yes' to continue: ") != "YES" {
+			return fmt.Errorf("cancelled")
 		}
+	}
 
-		return nil
-	} else {
-		return fmt.Errorf("invalid method: %s", method)
+	if !dryRun {
+		fmt.Printf("Delete %d job groups and their jobs ... \n", len(jobgroups))
 	}
+	report := instance.DeleteJobGroups(jobgroups, deleteOptions(args, "job group", len(jobgroups)))
+	return deleteSummary(report, "job groups", dryRun)
 }
 
-func runParentGroups(args []string) error {
-	method := "GET"
-
-	if len(args) > 0 {
-		// get method
-		method = args[0]
-		args = args[1:]
+func listParentGroups(args *Args) error {
+	if len(args.Positional) > 0 {
+		return fmt.Errorf("too many arguments")
 	}
-
-	method = strings.ToUpper(strings.TrimSpace(method))
-	if method == "GET" {
-		if jobgroups, err := instance.GetParentJobGroups(); err != nil {
-			return err
-		} else {
-			return printJson(jobgroups)
-		}
-	} else if method == "POST" {
-		return postParentJobGroups(args)
+	if jobgroups, err := instance.GetParentJobGroups(); err != nil {
+		return err
 	} else {
-		return fmt.Errorf("invalid method: %s", method)
+		return printData(jobgroups)
 	}
 }
 
-func runJobGroup(args []string) error {
-	method := "GET"
-
-	if len(args) > 0 {
-		// get method
-		method = args[0]
-		args = args[1:]
+func getJobGroups(args *Args) error {
+	ids, err := resolveJobGroups(args.Positional)
+	if err != nil {
+		return err
 	}
-
-	method = strings.ToUpper(strings.TrimSpace(method))
-	if method == "GET" {
-		ids, args := extractIntegers(args)
-		if len(args) > 0 {
-			return fmt.Errorf("too many arguments")
-		}
-		for _, id := range ids {
-			if jobgroup, err := instance.GetJobGroup(id); err != nil {
-				return err
-			} else {
-				if err := printJson(jobgroup); err != nil {
-					return err
-				}
-			}
-		}
-		return nil
-	} else if method == "POST" {
-		return postJobGroups(args)
-	} else if method == "DELETE" {
-		ids, args := extractIntegers(args)
-		if len(args) > 0 {
-			return fmt.Errorf("invalid arguments")
-		}
-		for _, id := range ids {
-			if err := instance.DeleteJobGroup(id); err != nil {
+	if len(ids) == 0 {
+		return fmt.Errorf("missing job group ids")
+	}
+	for _, id := range ids {
+		if jobgroup, err := instance.GetJobGroup(id); err != nil {
+			return err
+		} else {
+			if err := printData(jobgroup); err != nil {
 				return err
 			}
-			fmt.Printf("Deleted job group %d\n", id)
 		}
-		return nil
-	} else {
-		return fmt.Errorf("invalid method: %s", method)
 	}
+	return nil
 }
 
-func runParentGroup(args []string) error {
-	method := "GET"
-
-	if len(args) > 0 {
-		// get method
-		method = args[0]
-		args = args[1:]
+func deleteJobGroups(args *Args) error {
+	ids, err := resolveJobGroups(args.Positional)
+	if err != nil {
+		return err
 	}
-
-	method = strings.ToUpper(strings.TrimSpace(method))
-	if method == "GET" {
-		ids, args := extractIntegers(args)
-		if len(args) > 0 {
-			return fmt.Errorf("too many arguments")
+	if len(ids) == 0 {
+		return fmt.Errorf("missing job group ids")
+	}
+	for _, id := range ids {
+		if err := instance.DeleteJobGroup(id); err != nil {
+			return err
 		}
-		for _, id := range ids {
-			if jobgroup, err := instance.GetParentJobGroup(id); err != nil {
+		fmt.Printf("Deleted job group %d\n", id)
+	}
+	return nil
+}
+
+func getParentGroups(args *Args) error {
+	ids, rem := extractIntegers(args.Positional)
+	if len(rem) > 0 {
+		return fmt.Errorf("invalid arguments")
+	}
+	if len(ids) == 0 {
+		return fmt.Errorf("missing parent group ids")
+	}
+	for _, id := range ids {
+		if jobgroup, err := instance.GetParentJobGroup(id); err != nil {
+			return err
+		} else {
+			if err := printData(jobgroup); err != nil {
 				return err
-			} else {
-				if err := printJson(jobgroup); err != nil {
-					return err
-				}
 			}
 		}
-		return nil
-	} else if method == "POST" {
-		return postParentJobGroups(args)
-	} else {
-		return fmt.Errorf("invalid method: %s", method)
 	}
+	return nil
 }
@@ -5,7 +5,6 @@ import (
 	"fmt"
 	"io"
 	"os"
-	"strings"
 
 	"github.com/os-autoinst/gopenqa"
 )
@@ -52,8 +51,33 @@ func readMachines(filename string) ([]gopenqa.Machine, error) {
 	return machines, fmt.Errorf("invalid input format")
 }
 
-func postMachines(args []string) error {
-	files := args
+/* Resolve machine IDs or names to IDs */
+func resolveMachines(values []string) ([]int, error) {
+	ids, names := extractIntegers(values)
+	if len(names) == 0 {
+		return ids, nil
+	}
+	machines, err := instance.GetMachines()
+	if err != nil {
+		return ids, err
+	}
+	for _, name := range names {
+		found := false
+		for _, machine := range machines {
+			if machine.Name == name {
+				ids = append(ids, machine.ID)
+				found = true
+			}
+		}
+		if !found {
+			return ids, fmt.Errorf("machine not found: %s", name)
+		}
+	}
+	return ids, nil
+}
+
+func postMachines(args *Args) error {
+	files := args.Positional
 	if len(files) == 0 {
 		files = append(files, "")
 	}
@@ -75,95 +99,89 @@ func postMachines(args []string) error {
 	return nil
 }
 
-func runMachines(args []string) error {
-	method := "GET"
-
-	if len(args) > 0 {
-		// get method
-		method = args[0]
-		args = args[1:]
+func listMachines(args *Args) error {
+	if len(args.Positional) > 0 {
+		return fmt.Errorf("too many arguments")
+	}
+	if machines, err := instance.GetMachines(); err != nil {
+		return err
+	} else {
+		return printData(machines)
 	}
+}
 
-	method = strings.ToUpper(strings.TrimSpace(method))
-	if method == "GET" {
-		if machines, err := instance.GetMachines(); err != nil {
+func deleteMachines(args *Args) error {
+	ids, err := resolveMachines(args.Positional)
+	if err != nil {
+		return err
+	}
+	if len(ids) == 0 {
+		return fmt.Errorf("missing machine ids")
+	}
+	for _, id := range ids {
+		if err := instance.DeleteMachine(id); err != nil {
 			return err
 		} else {
-			return printJson(machines)
-		}
-	} else if method == "POST" {
-		return postMachines(args)
-	} else if method == "DELETE" {
-		ids, _ := extractIntegers(args)
-		if len(ids) == 0 {
-			fmt.Fprintf(os.Stderr, "Missing machine ids\n")
-		} else {
-			for _, id := range ids {
-				if err := instance.DeleteMachine(id); err != nil {
-					return err
-				} else {
-					fmt.Printf("Deleted machine %d\n", id)
-				}
-			}
+			fmt.Printf("Deleted machine %d\n", id)
 		}
+	}
+	return nil
+}
+
+func clearMachines(args *Args) error {
+	filter, filtered, err := parseDeleteFilter(args)
+	if err != nil {
+		return err
+	}
+
+	// Get machines and then delete them one by one
+	if cf.Verbose {
+		fmt.Println("Fetching machines ... ")
+	}
+	machines, err := instance.FilterMachines(filter)
+	if err != nil {
+		return err
+	}
+	if len(machines) == 0 {
+		fmt.Println("No machines to delete")
 		return nil
-	} else if method == "CLEAR" {
-		if !cf.NoPrompt {
+	}
+	dryRun := args.Has("dry-run")
+	if !dryRun && !cf.NoPrompt {
+		if filtered {
+			for _, machine := range machines {
+				fmt.Printf("  %d %s:%s\n", machine.ID, machine.Name, machine.Backend)
+			}
+			fmt.Printf("Are you sure you want to delete %d machines? THERE WILL BE NO UNDO.\n", len(machines))
+		} else {
 			fmt.Println("DANGER ZONE !!")
 			fmt.Println("Are you sure you want to delete ALL machines? THERE WILL BE NO UNDO, if you are hesitant then stop NOW.")
-			if prompt("Type uppercase 'yes' to continue: ") != "YES" {
-				return fmt.Errorf("cancelled")
-			}
-		}
-
-		// Get machines and then delete them one by one
-		if cf.Verbose {
-			fmt.Println("Fetching machines ... ")
 		}
-		if machines, err := instance.GetMachines(); err != nil {
-			return err
-		} else {
-			for i, machine := range machines {
-				id := machine.ID
-				if err := instance.DeleteMachine(id); err != nil {
-					return err
-				} else {
-					fmt.Printf("[%d/%d] Deleted machine %d %s:%s\n", i, len(machines), machine.ID, machine.Name, machine.Backend)
-				}
-			}
+		if prompt("Type uppercase 'yes' to continue: ") != "YES" {
+			return fmt.Errorf("cancelled")
 		}
-
-		return nil
-	} else {
-		return fmt.Errorf("invalid method: %s", method)
 	}
-}
 
-func runMachine(args []string) error {
-	method := "GET"
-	ids, args := extractIntegers(args)
+	report := instance.DeleteMachines(machines, deleteOptions(args, "machine", len(machines)))
+	return deleteSummary(report, "machines", dryRun)
+}
 
-	if len(args) > 0 {
-		// get method
-		method = args[0]
-		args = args[1:]
+func getMachines(args *Args) error {
+	ids, err := resolveMachines(args.Positional)
+	if err != nil {
+		return err
 	}
-
-	method = strings.ToUpper(strings.TrimSpace(method))
-	if method == "GET" {
-		for _, id := range ids {
-			if machines, err := instance.GetMachine(id); err != nil {
+	if len(ids) == 0 {
+		return fmt.Errorf("missing machine ids")
+	}
+	for _, id := range ids {
+		if machines, err := instance.GetMachine(id); err != nil {
+			return err
+		} else {
+			if err := printData(machines); err != nil {
 				return err
-			} else {
-				if err := printJson(machines); err != nil {
-					return err
-				}
 			}
 		}
-		return nil
-	} else if method == "POST" {
-		return postMachines(args)
-	} else {
-		return fmt.Errorf("invalid method: %s", method)
 	}
+	return nil
 }
@@ -0,0 +1,251 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Supported output formats
+var outputFormats = []string{"json", "pretty", "yaml", "table", "csv", "ids"}
+
+// Default columns for the table and csv output per type. Types not listed here show all fields
+var defaultColumns = map[string][]string{
+	"Job":            {"id", "name", "state", "result", "group_id", "link"},
+	"JobGroup":       {"id", "name", "parent_id", "sort_order", "description"},
+	"Machine":        {"id", "name", "backend"},
+	"Product":        {"id", "distri", "version", "flavor", "arch"},
+	"TestSuite":      {"id", "name", "description"},
+	"JobTemplate":    {"id", "group_name", "test_suite.name", "product.distri", "product.version", "product.flavor", "product.arch", "machine.name", "prio"},
+	"Comment":        {"id", "userName", "created", "text"},
+	"Worker":         {"id", "host", "instance", "status", "jobid", "link"},
+	"WorkerCapacity": {"worker_class", "idle", "busy", "offline", "broken", "total"},
+	"Event":          {"type", "key"},
+}
+
+func isOutputFormat(format string) bool {
+	for _, f := range outputFormats {
+		if f == format {
+			return true
+		}
+	}
+	return false
+}
+
+func printJson(data interface{}) error {
+	// Print as json
+	if buf, err := json.Marshal(data); err != nil {
+		return err
+	} else {
+		fmt.Println(string(buf))
+		return nil
+	}
+}
+
+/* Print the given data in the configured output format */
+func printData(data interface{}) error {
+	switch cf.Output {
+	case "", "json":
+		return printJson(data)
+	case "pretty":
+		buf, err := json.MarshalIndent(data, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(buf))
+		return nil
+	case "yaml":
+		// Convert via JSON first, so that the keys are the same as in the JSON output
+		generic, err := toGeneric(data)
+		if err != nil {
+			return err
+		}
+		buf, err := yaml.Marshal(generic)
+		if err != nil {
+			return err
+		}
+		fmt.Print(string(buf))
+		return nil
+	case "table", "csv", "ids":
+		columns := cf.Columns
+		if cf.Output == "ids" {
+			columns = []string{"id"}
+		} else if len(columns) == 0 {
+			columns = columnsOf(data)
+		}
+		rows, err := toRows(data, columns)
+		if err != nil {
+			return err
+		}
+		if cf.Output == "table" {
+			return printTable(columns, rows)
+		} else if cf.Output == "csv" {
+			return printCSV(columns, rows)
+		}
+		for _, row := range rows {
+			fmt.Println(row[0])
+		}
+		return nil
+	}
+	return fmt.Errorf("invalid output format: %s", cf.Output)
+}
+
+// toGeneric converts the given data into maps and slices via its JSON representation
+func toGeneric(data interface{}) (interface{}, error) {
+	var generic interface{}
+	buf, err := json.Marshal(data)
+	if err != nil {
+		return generic, err
+	}
+	err = json.Unmarshal(buf, &generic)
+	return generic, err
+}
+
+// elementType returns the (dereferenced) type of the given value or of its elements, if it is a slice
+func elementType(data interface{}) reflect.Type {
+	t := reflect.TypeOf(data)
+	for t != nil && (t.Kind() == reflect.Ptr || t.Kind() == reflect.Slice || t.Kind() == reflect.Array) {
+		t = t.Elem()
+	}
+	return t
+}
+
+// columnsOf returns the default columns for the given data. For unknown types all fields are returned in their declaration order
+func columnsOf(data interface{}) []string {
+	t := elementType(data)
+	if t == nil {
+		return []string{}
+	}
+	if columns, ok := defaultColumns[t.Name()]; ok {
+		return columns
+	}
+	columns := make([]string, 0)
+	if t.Kind() == reflect.Struct {
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if !field.IsExported() {
+				continue
+			}
+			name := strings.Split(field.Tag.Get("json"), ",")[0]
+			if name == "-" {
+				continue
+			} else if name == "" {
+				name = field.Name
+			}
+			columns = append(columns, name)
+		}
+		return columns
+	}
+	// Maps or plain values
+	generic, _ := toGeneric(data)
+	if values, ok := generic.([]interface{}); ok && len(values) > 0 {
+		generic = values[0]
+	}
+	if obj, ok := generic.(map[string]interface{}); ok {
+		for k := range obj {
+			columns = append(columns, k)
+		}
+		sort.Strings(columns)
+		return columns
+	}
+	return []string{"value"}
+}
+
+// lookup returns the value of the given column. Nested values are accessed via dots, e.g. "settings.ARCH"
+func lookup(obj interface{}, column string) interface{} {
+	for _, key := range strings.Split(column, ".") {
+		m, ok := obj.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		obj = m[key]
+	}
+	return obj
+}
+
+// formatValue formats a single value for the table and csv output
+func formatValue(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case float64:
+		// JSON numbers are float64, but are mostly IDs
+		if v == float64(int64(v)) {
+			return fmt.Sprintf("%d", int64(v))
+		}
+		return fmt.Sprintf("%g", v)
+	case []interface{}:
+		values := make([]string, 0)
+		for _, e := range v {
+			values = append(values, formatValue(e))
+		}
+		return strings.Join(values, ",")
+	case map[string]interface{}:
+		buf, _ := json.Marshal(v)
+		return string(buf)
+	}
+	return fmt.Sprintf("%v", value)
+}
+
+// toRows converts the given data (a single object or a list of objects) into rows with the given columns
+func toRows(data interface{}, columns []string) ([][]string, error) {
+	generic, err := toGeneric(data)
+	if err != nil {
+		return nil, err
+	}
+	values, ok := generic.([]interface{})
+	if !ok {
+		values = []interface{}{generic}
+	}
+	rows := make([][]string, 0)
+	for _, value := range values {
+		row := make([]string, 0)
+		for _, column := range columns {
+			if _, ok := value.(map[string]interface{}); !ok && column == "value" {
+				row = append(row, formatValue(value))
+			} else {
+				row = append(row, formatValue(lookup(value, column)))
+			}
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+func printTable(columns []string, rows [][]string) error {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	header := make([]string, 0)
+	for _, column := range columns {
+		header = append(header, strings.ToUpper(column))
+	}
+	fmt.Fprintln(w, strings.Join(header, "\t"))
+	for _, row := range rows {
+		// Newlines break the table layout
+		for i := range row {
+			row[i] = strings.ReplaceAll(row[i], "\n", " ")
+		}
+		fmt.Fprintln(w, strings.Join(row, "\t"))
+	}
+	return w.Flush()
+}
+
+func printCSV(columns []string, rows [][]string) error {
+	w := csv.NewWriter(os.Stdout)
+	if err := w.Write(columns); err != nil {
+		return err
+	}
+	if err := w.WriteAll(rows); err != nil {
+		return err
+	}
+	w.Flush()
+	return w.Error()
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"io"
+	"os"
+	"testing"
+
+	"github.com/os-autoinst/gopenqa"
+	"gotest.tools/assert"
+)
+
+// captureStdout returns everything the given function prints to stdout
+func captureStdout(t *testing.T, f func() error) string {
+	t.Helper()
+	r, w, err := os.Pipe()
+	assert.NilError(t, err)
+	stdout := os.Stdout
+	os.Stdout = w
+	defer func() { os.Stdout = stdout }()
+	output := make(chan string)
+	go func() {
+		buf, _ := io.ReadAll(r)
+		output <- string(buf)
+	}()
+	err = f()
+	w.Close()
+	os.Stdout = stdout
+	assert.NilError(t, err)
+	return <-output
+}
+
+// printOutput prints the data in the given output format and columns
+func printOutput(t *testing.T, format string, columns []string, data interface{}) string {
+	t.Helper()
+	output, selected := cf.Output, cf.Columns
+	defer func() { cf.Output, cf.Columns = output, selected }()
+	cf.Output, cf.Columns = format, columns
+	return captureStdout(t, func() error { return printData(data) })
+}
+
+var outputTemplates = []gopenqa.JobTemplate{
+	{ID: 1, GroupName: "openSUSE Tumbleweed", Priority: 50, Machine: gopenqa.Machine{ID: 2, Name: "64bit"}, Product: gopenqa.Product{Distri: "opensuse", Version: "Tumbleweed", Flavor: "DVD", Arch: "x86_64", Settings: map[string]string{"ISO_MAXSIZE": "4700372992"}}, TestSuite: gopenqa.TestSuite{Name: "textmode"}},
+	{ID: 2, GroupName: "Quoting, \"tests\"", Priority: 40, Machine: gopenqa.Machine{ID: 3, Name: "uefi"}, TestSuite: gopenqa.TestSuite{Name: "multi\nline"}},
+}
+
+func TestColumnsOf(t *testing.T) {
+	type unknown struct {
+		ID       int    `json:"id"`
+		Name     string `json:"name,omitempty"`
+		Internal string `json:"-"`
+		Untagged string
+		hidden   string
+	}
+	tests := []struct {
+		name    string
+		data    interface{}
+		columns []string
+	}{
+		{"defaults", outputTemplates, defaultColumns["JobTemplate"]},
+		{"pointer", &gopenqa.Machine{}, defaultColumns["Machine"]},
+		{"unknown struct", []unknown{{hidden: "x"}}, []string{"id", "name", "Untagged"}},
+		{"map", []map[string]int{{"total": 1, "busy": 2}}, []string{"busy", "total"}},
+		{"plain values", []string{"a", "b"}, []string{"value"}},
+		{"nil", nil, []string{}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.DeepEqual(t, columnsOf(test.data), test.columns)
+		})
+	}
+}
+
+func TestToRows(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    interface{}
+		columns []string
+		rows    [][]string
+	}{
+		{"nested fields", outputTemplates, []string{"id", "machine.name", "product.settings.ISO_MAXSIZE", "test_suite.name"},
+			[][]string{{"1", "64bit", "4700372992", "textmode"}, {"2", "uefi", "", "multi\nline"}}},
+		{"missing and non-object fields", outputTemplates[:1], []string{"nonexisting", "id.value", "machine"},
+			[][]string{{"", "", `{"backend":"","id":2,"name":"64bit","settings":null}`}}},
+		{"single object", outputTemplates[0], []string{"group_name", "prio"}, [][]string{{"openSUSE Tumbleweed", "50"}}},
+		{"lists and floats", map[string]interface{}{"ids": []int{1, 2, 3}, "ratio": 0.5}, []string{"ids", "ratio"}, [][]string{{"1,2,3", "0.5"}}},
+		{"plain values", []string{"a", "b"}, []string{"value"}, [][]string{{"a"}, {"b"}}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			rows, err := toRows(test.data, test.columns)
+			assert.NilError(t, err)
+			assert.DeepEqual(t, rows, test.rows)
+		})
+	}
+}
+
+func TestPrintData(t *testing.T) {
+	tests := []struct {
+		format  string
+		columns []string
+		output  string
+	}{
+		{"csv", []string{"id", "group_name", "test_suite.name"}, "id,group_name,test_suite.name\n1,openSUSE Tumbleweed,textmode\n2,\"Quoting, \"\"tests\"\"\",\"multi\nline\"\n"},
+		{"table", []string{"id", "test_suite.name", "prio"}, "ID  TEST_SUITE.NAME  PRIO\n1   textmode         50\n2   multi line       40\n"},
+		{"ids", nil, "1\n2\n"},
+		// The ids output ignores the selected columns
+		{"ids", []string{"group_name"}, "1\n2\n"},
+	}
+	for _, test := range tests {
+		t.Run(test.format, func(t *testing.T) {
+			assert.Equal(t, printOutput(t, test.format, test.columns, outputTemplates), test.output)
+		})
+	}
+
+	// Default columns are used without a column selection
+	output := printOutput(t, "csv", nil, []gopenqa.Machine{{ID: 1, Name: "64bit", Backend: "qemu"}})
+	assert.Equal(t, output, "id,name,backend\n1,64bit,qemu\n")
+	cf.Output = "invalid"
+	defer func() { cf.Output = "" }()
+	assert.ErrorContains(t, printData(outputTemplates), "invalid output format")
+}
@@ -5,7 +5,6 @@ import (
 	"fmt"
 	"io"
 	"os"
-	"strings"
 
 	"github.com/os-autoinst/gopenqa"
 )
@@ -51,8 +50,8 @@ func readProducts(filename string) ([]gopenqa.Product, error) {
 	return products, fmt.Errorf("invalid input format")
 }
 
-func postProduct(args []string) error {
-	files := args
+func postProducts(args *Args) error {
+	files := args.Positional
 	if len(files) == 0 {
 		files = append(files, "")
 	}
@@ -73,61 +72,37 @@ func postProduct(args []string) error {
 	return nil
 }
 
-func runProducts(args []string) error {
-	method := "GET"
-
-	if len(args) > 0 {
-		// get method
-		method = args[0]
-		args = args[1:]
+func listProducts(args *Args) error {
+	if len(args.Positional) > 0 {
+		return fmt.Errorf("too many arguments")
 	}
-
-	method = strings.ToUpper(strings.TrimSpace(method))
-	if method == "GET" {
-		products, err := instance.GetProducts()
-		if err != nil {
-			return err
-		}
-		if err := printJson(products); err != nil {
-			return err
-		}
-		return nil
-	} else if method == "POST" {
-		return postProduct(args)
-	} else {
-		return fmt.Errorf("invalid method: %s", method)
+	products, err := instance.GetProducts()
+	if err != nil {
+		return err
+	}
+	if err := printData(products); err != nil {
+		return err
 	}
+	return nil
 }
 
-func runProduct(args []string) error {
-	method := "GET"
-
-	if len(args) > 0 {
-		// get method
-		method = args[0]
-		args = args[1:]
+func getProducts(args *Args) error {
+	ids, rem := extractIntegers(args.Positional)
+	if len(rem) > 0 {
+		return fmt.Errorf("invalid arguments")
 	}
-
-	method = strings.ToUpper(strings.TrimSpace(method))
-	if method == "GET" {
-		ids, _ := extractIntegers(args)
-		if len(ids) == 0 {
-			return fmt.Errorf("missing product ids")
+	if len(ids) == 0 {
+		return fmt.Errorf("missing product ids")
+	}
+	for _, id := range ids {
+		product, err := instance.GetProduct(id)
+		if err != nil {
+			return err
 		}
-		for _, id := range ids {
-			product, err := instance.GetProduct(id)
-			if err != nil {
-				return err
-			}
 
-			if err := printJson(product); err != nil {
-				return err
-			}
+		if err := printData(product); err != nil {
+			return err
 		}
-		return nil
-	} else if method == "POST" {
-		return postProduct(args)
-	} else {
-		return fmt.Errorf("invalid method: %s", method)
 	}
+	return nil
 }
@@ -3,25 +3,16 @@ package main
 import (
 	"fmt"
 	"os"
-	"strings"
 )
 
-func runJobTemplates(args []string) error {
-	method := "GET"
-
-	if len(args) > 0 {
-		// get method
+func listJobTemplates(args *Args) error {
+	if len(args.Positional) > 0 {
+		return fmt.Errorf("too many arguments")
 	}
-
-	method = strings.ToUpper(strings.TrimSpace(method))
-	if method == "GET" {
-		fmt.Fprintf(os.Stderr, "Getting job templates ... ")
-		templates, err := instance.GetJobTemplates()
-		if err != nil {
-			return err
-		}
-		return printJson(templates)
-	} else {
-		return fmt.Errorf("invalid method: %s", method)
+	fmt.Fprintf(os.Stderr, "Getting job templates ... ")
+	templates, err := instance.GetJobTemplates()
+	if err != nil {
+		return err
 	}
+	return printData(templates)
 }
@@ -2,11 +2,11 @@ package main
 
 import (
 	"bufio"
-	"encoding/json"
 	"fmt"
 	"os"
 	"strconv"
 	"strings"
+	"time"
 )
 
 func extractIntegers(args []string) ([]int, []string) {
@@ -23,6 +23,15 @@ func extractIntegers(args []string) ([]int, []string) {
 	return ids, rem
 }
 
+// Parse a job ID
+func parseJobID(arg string) (int64, error) {
+	id, err := strconv.ParseInt(arg, 10, 64)
+	if err != nil || id <= 0 {
+		return 0, fmt.Errorf("invalid ID: %s", arg)
+	}
+	return id, nil
+}
+
 func prompt(msg string) string {
 	if msg != "" {
 		fmt.Print(msg)
@@ -35,28 +44,14 @@ func prompt(msg string) string {
 	return strings.TrimSpace(line)
 }
 
-// Replace some magic by their remote
-func magicRemote(remote string) string {
-	// Skip if remote
-	if strings.HasPrefix(remote, "http://") || strings.HasPrefix(remote, "https://") {
-		return remote
-	}
-	if remote == "" || remote == "ooo" || remote == "o3" {
-		return "https://openqa.opensuse.org"
-	} else if remote == "osd" {
-		return "http://openqa.suse.de"
-	} else if remote == "duck" {
-		return "http://duck-norris.qam.suse.de"
-	}
-	return remote
-}
-
-func printJson(data interface{}) error {
-	// Print as json
-	if buf, err := json.Marshal(data); err != nil {
-		return err
-	} else {
-		fmt.Println(string(buf))
-		return nil
+// Parse a duration, which in addition to time.ParseDuration supports days (e.g. "7d")
+func parseDuration(value string) (time.Duration, error) {
+	if strings.HasSuffix(value, "d") {
+		days, err := strconv.Atoi(strings.TrimSuffix(value, "d"))
+		if err != nil {
+			return 0, fmt.Errorf("invalid duration: %s", value)
+		}
+		return time.Duration(days) * 24 * time.Hour, nil
 	}
+	return time.ParseDuration(value)
 }
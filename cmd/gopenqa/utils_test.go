@@ -0,0 +1,33 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"gotest.tools/assert"
+)
+
+func TestParseDuration(t *testing.T) {
+	tests := []struct {
+		value    string
+		duration time.Duration
+		valid    bool
+	}{
+		{"90d", 90 * 24 * time.Hour, true},
+		{"1d", 24 * time.Hour, true},
+		{"0d", 0, true},
+		{"36h", 36 * time.Hour, true},
+		{"1h30m", 90 * time.Minute, true},
+		{"d", 0, false},
+		{"1.5d", 0, false},
+		{"7days", 0, false},
+		{"", 0, false},
+	}
+	for _, test := range tests {
+		duration, err := parseDuration(test.value)
+		assert.Equal(t, err == nil, test.valid, test.value)
+		if test.valid {
+			assert.Equal(t, duration, test.duration, test.value)
+		}
+	}
+}
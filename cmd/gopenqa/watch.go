@@ -0,0 +1,218 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/os-autoinst/gopenqa"
+)
+
+// Exit code of watch, if not all jobs passed
+const EXIT_JOBS_FAILED = 2
+
+// Routing key for the job events of watch
+const WATCH_AMQP_KEY = "#.openqa.job.*"
+
+// Interval for refreshing the jobs if they are watched via RabbitMQ. Events may get lost, e.g. on reconnects
+const WATCH_EVENT_REFRESH = 5 * time.Minute
+
+/* Jobs being watched, either given by their IDs or by build and job group */
+type watchedJobs struct {
+	ids     []int64
+	build   string
+	groupID int
+}
+
+// fetch returns the current jobs. Restarted jobs are replaced by their clones
+func (w *watchedJobs) fetch() ([]gopenqa.Job, error) {
+	if len(w.ids) > 0 {
+		return instance.GetJobsFollow(w.ids)
+	}
+	return instance.SearchJobs(gopenqa.JobQuery{Build: w.build, GroupID: w.groupID, Latest: true, FollowClones: true})
+}
+
+// relevant returns true if the event may change any of the given jobs
+func (w *watchedJobs) relevant(event gopenqa.Event, jobs []gopenqa.Job) bool {
+	if event.Category() != "job" {
+		return false
+	}
+	if len(w.ids) == 0 {
+		return event.Build() == w.build && (w.groupID == 0 || event.GroupID() == w.groupID)
+	}
+	id := event.JobID()
+	for _, job := range jobs {
+		if job.ID == id {
+			return true
+		}
+	}
+	return false
+}
+
+// isFinal returns true if the job will not change anymore
+func isFinal(job gopenqa.Job) bool {
+	return job.State == "done" || job.State == "cancelled"
+}
+
+// isPassed returns true if the job finished with an acceptable result
+func isPassed(job gopenqa.Job) bool {
+	return job.State == "done" && (job.Result == "passed" || job.Result == "softfailed")
+}
+
+// isTerminal returns true if stdout is a terminal
+func isTerminal() bool {
+	stat, err := os.Stdout.Stat()
+	return err == nil && stat.Mode()&os.ModeCharDevice != 0
+}
+
+// renderJobs returns the table rows of the given jobs and the number of jobs per state
+func renderJobs(jobs []gopenqa.Job) ([][]string, map[string]int) {
+	rows := make([][]string, 0)
+	counts := make(map[string]int, 0)
+	for _, job := range jobs {
+		rows = append(rows, []string{fmt.Sprintf("%d", job.ID), job.Test, job.Settings.Arch, job.Settings.Machine, job.JobState(), job.Link})
+		counts[job.JobState()]++
+	}
+	return rows, counts
+}
+
+/* Watch jobs until all of them are finished. Exits with EXIT_JOBS_FAILED if any job did not pass */
+func runWatch(args *Args) error {
+	var watched watchedJobs
+	var err error
+	for _, arg := range args.Positional {
+		id, err := parseJobID(arg)
+		if err != nil {
+			return err
+		}
+		watched.ids = append(watched.ids, id)
+	}
+	watched.build = args.Value("build")
+	if args.Has("group") {
+		ids, err := resolveJobGroups([]string{args.Value("group")})
+		if err != nil {
+			return err
+		}
+		watched.groupID = ids[0]
+	}
+	if len(watched.ids) > 0 && (watched.build != "" || watched.groupID != 0) {
+		return fmt.Errorf("either job IDs or --build and --group can be given")
+	} else if len(watched.ids) == 0 && watched.build == "" {
+		return fmt.Errorf("missing job IDs or --build")
+	}
+	poll := 30 * time.Second
+	if args.Has("interval") {
+		if poll, err = parseDuration(args.Value("interval")); err != nil {
+			return err
+		}
+		if poll <= 0 {
+			return fmt.Errorf("invalid interval: %s", args.Value("interval"))
+		}
+	}
+	interval := poll
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	if args.Has("timeout") {
+		timeout, err := parseDuration(args.Value("timeout"))
+		if err != nil {
+			return err
+		}
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	// Use RabbitMQ events for refreshing, if a broker is configured. Otherwise poll
+	var events <-chan gopenqa.Event
+	remote := cf.AMQP
+	if args.Has("amqp") {
+		remote = args.Value("amqp")
+	}
+	if remote != "" {
+		if rmq, err := gopenqa.ConnectRabbitMQ(remote); err != nil {
+			fmt.Fprintf(os.Stderr, "RabbitMQ unavailable, falling back to polling: %s\n", err)
+		} else {
+			defer rmq.Close()
+			sub, err := rmq.SubscribeWithOptions(gopenqa.RabbitMQSubscribeOptions{Keys: []string{WATCH_AMQP_KEY}})
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "RabbitMQ unavailable, falling back to polling: %s\n", err)
+			} else {
+				defer sub.Close()
+				events = sub.Events(ctx)
+				interval = WATCH_EVENT_REFRESH
+			}
+		}
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	terminal := isTerminal()
+	previous := ""
+	for {
+		jobs, err := watched.fetch()
+		if err != nil {
+			return err
+		}
+		finished := len(jobs) > 0
+		for _, job := range jobs {
+			finished = finished && isFinal(job)
+		}
+
+		if cf.Output == "" {
+			rows, counts := renderJobs(jobs)
+			current := fmt.Sprintf("%v", rows)
+			if terminal {
+				// Clear screen
+				fmt.Print("\033[H\033[2J")
+			}
+			if terminal || current != previous {
+				fmt.Printf("%s - %d jobs, %d running, %d scheduled (%s)\n", instance.URL, len(jobs), counts["running"], counts["scheduled"], time.Now().Format("15:04:05"))
+				if err := printTable([]string{"id", "test", "arch", "machine", "result", "link"}, rows); err != nil {
+					return err
+				}
+			}
+			previous = current
+		}
+		if finished {
+			if cf.Output != "" {
+				if err := printData(jobs); err != nil {
+					return err
+				}
+			}
+			failed := 0
+			for _, job := range jobs {
+				if !isPassed(job) {
+					failed++
+				}
+			}
+			if failed > 0 {
+				return exitError{code: EXIT_JOBS_FAILED, err: fmt.Errorf("%d of %d jobs did not pass", failed, len(jobs))}
+			}
+			return nil
+		}
+
+		// Wait for the next refresh
+		for refresh := false; !refresh; {
+			select {
+			case <-ctx.Done():
+				if ctx.Err() == context.DeadlineExceeded {
+					return fmt.Errorf("timeout")
+				}
+				return fmt.Errorf("cancelled")
+			case <-ticker.C:
+				refresh = true
+			case event, ok := <-events:
+				if !ok {
+					// Subscription closed, fall back to polling
+					events = nil
+					ticker.Reset(poll)
+					refresh = true
+				} else {
+					refresh = watched.relevant(event, jobs)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/os-autoinst/gopenqa"
+	"gotest.tools/assert"
+)
+
+func TestWatchRelevant(t *testing.T) {
+	jobs := []gopenqa.Job{{ID: 1}, {ID: 2}}
+	byID := watchedJobs{ids: []int64{1, 2}}
+	byBuild := watchedJobs{build: "20230101"}
+	byGroup := watchedJobs{build: "20230101", groupID: 7}
+	jobEvent := func(id int64, build string, group int) gopenqa.Event {
+		return gopenqa.Event{Type: "job.done", Job: &gopenqa.JobStatus{ID: id, Build: build, GroupID: group}}
+	}
+	tests := []struct {
+		name     string
+		watched  watchedJobs
+		event    gopenqa.Event
+		relevant bool
+	}{
+		{"watched job", byID, jobEvent(2, "", 0), true},
+		{"other job", byID, jobEvent(3, "", 0), false},
+		{"comment on watched job", byID, gopenqa.Event{Type: "comment.create", Comment: &gopenqa.CommentMQ{JobID: 1}}, false},
+		{"job of the build", byBuild, jobEvent(3, "20230101", 7), true},
+		{"job of another build", byBuild, jobEvent(1, "20230102", 7), false},
+		{"job of the build and group", byGroup, jobEvent(3, "20230101", 7), true},
+		{"job of the build in another group", byGroup, jobEvent(3, "20230101", 8), false},
+	}
+	for _, test := range tests {
+		assert.Equal(t, test.watched.relevant(test.event, jobs), test.relevant, test.name)
+	}
+}
+
+func TestWatchPassed(t *testing.T) {
+	tests := []struct {
+		state  string
+		result string
+		final  bool
+		passed bool
+	}{
+		{"done", "passed", true, true},
+		{"done", "softfailed", true, true},
+		{"done", "failed", true, false},
+		{"done", "incomplete", true, false},
+		{"cancelled", "user_cancelled", true, false},
+		{"running", "none", false, false},
+		{"scheduled", "none", false, false},
+	}
+	for _, test := range tests {
+		job := gopenqa.Job{State: test.state, Result: test.result}
+		assert.Equal(t, isFinal(job), test.final, test.state+"/"+test.result)
+		assert.Equal(t, isPassed(job), test.passed, test.state+"/"+test.result)
+	}
+}
+
+// watch runs the watch command with the given arguments and returns its error
+func watch(t *testing.T, argv ...string) error {
+	t.Helper()
+	_, args, err := parseCommand(commands(), append([]string{"watch"}, argv...))
+	assert.NilError(t, err)
+	captureStdout(t, func() error {
+		err = runWatch(args)
+		return nil
+	})
+	return err
+}
+
+// exitCode returns the exit code of the program for the given error of a command, like main
+func exitCode(err error) int {
+	if e, ok := err.(exitError); ok {
+		return e.code
+	}
+	if err != nil {
+		return 1
+	}
+	return 0
+}
+
+func TestWatch(t *testing.T) {
+	server := fakeServer(t)
+	build := gopenqa.Settings{Build: "20230101"}
+	passed := server.AddJob(gopenqa.Job{Test: "minimal", State: "done", Result: "passed", GroupID: 7, Settings: build})
+	softfailed := server.AddJob(gopenqa.Job{Test: "textmode", State: "done", Result: "softfailed", GroupID: 7, Settings: build})
+	failed := server.AddJob(gopenqa.Job{Test: "kde", State: "done", Result: "failed", GroupID: 8, Settings: build})
+	// Restarted jobs are replaced by their clones
+	clone := server.AddJob(gopenqa.Job{Test: "gnome", State: "done", Result: "passed", GroupID: 8})
+	restarted := server.AddJob(gopenqa.Job{Test: "gnome", State: "done", Result: "failed", GroupID: 8, CloneID: clone.ID})
+
+	ids := func(jobs ...gopenqa.Job) []string {
+		ret := make([]string, 0)
+		for _, job := range jobs {
+			ret = append(ret, fmt.Sprintf("%d", job.ID))
+		}
+		return ret
+	}
+	tests := []struct {
+		name string
+		args []string
+		code int
+		err  string
+	}{
+		{"passed and softfailed", ids(passed, softfailed), 0, ""},
+		{"failed", ids(passed, failed), EXIT_JOBS_FAILED, "1 of 2 jobs did not pass"},
+		{"restarted", ids(passed, restarted), 0, ""},
+		{"build and group", []string{"--build", "20230101", "--group", "7"}, 0, ""},
+		{"build", []string{"--build", "20230101"}, EXIT_JOBS_FAILED, "1 of 3 jobs did not pass"},
+		{"IDs and build", append(ids(passed), "--build", "20230101"), 1, "either job IDs or --build and --group can be given"},
+		{"nothing to watch", []string{}, 1, "missing job IDs or --build"},
+		{"zero interval", append(ids(passed), "--interval", "0"), 1, "invalid interval: 0"},
+		{"negative interval", append(ids(passed), "--interval", "-1s"), 1, "invalid interval: -1s"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := watch(t, test.args...)
+			assert.Equal(t, exitCode(err), test.code)
+			if test.err != "" {
+				assert.Error(t, err, test.err)
+			}
+		})
+	}
+
+	// Running jobs are polled until they finish
+	running := server.AddJob(gopenqa.Job{Test: "minimal", State: "running", Result: "none"})
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		server.SetJobState(running.ID, "done", "passed")
+	}()
+	assert.NilError(t, watch(t, fmt.Sprintf("%d", running.ID), "--interval", "10ms"))
+	running = server.AddJob(gopenqa.Job{Test: "minimal", State: "running", Result: "none"})
+	err := watch(t, fmt.Sprintf("%d", running.ID), "--interval", "10ms", "--timeout", "50ms")
+	assert.Equal(t, exitCode(err), 1)
+	assert.Error(t, err, "timeout")
+}
@@ -0,0 +1,200 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/os-autoinst/gopenqa"
+)
+
+/* Parse worker filters given as KEY=VALUE arguments (host, status, class) */
+func parseWorkerQuery(args []string) (gopenqa.WorkerQuery, error) {
+	var query gopenqa.WorkerQuery
+	for _, arg := range args {
+		i := strings.Index(arg, "=")
+		if i <= 0 {
+			return query, fmt.Errorf("invalid filter: %s", arg)
+		}
+		key, value := strings.ToLower(arg[:i]), arg[i+1:]
+		if key == "host" {
+			query.Host = value
+		} else if key == "status" || key == "state" {
+			query.Status = append(query.Status, strings.Split(value, ",")...)
+		} else if key == "class" || key == "worker_class" {
+			query.WorkerClass = append(query.WorkerClass, strings.Split(value, ",")...)
+		} else {
+			return query, fmt.Errorf("invalid filter: %s", key)
+		}
+	}
+	return query, nil
+}
+
+func listWorkers(args *Args) error {
+	query, err := parseWorkerQuery(args.Positional)
+	if err != nil {
+		return err
+	}
+	if workers, err := instance.QueryWorkers(query); err != nil {
+		return err
+	} else {
+		return printData(workers)
+	}
+}
+
+func workerCapacity(args *Args) error {
+	query, err := parseWorkerQuery(args.Positional)
+	if err != nil {
+		return err
+	}
+	workers, err := instance.QueryWorkers(query)
+	if err != nil {
+		return err
+	}
+	if cf.Output != "" {
+		return printData(gopenqa.WorkerCapacityReport(workers))
+	}
+	fmt.Printf("%-30s %6s %6s %8s %8s %6s\n", "WORKER_CLASS", "IDLE", "BUSY", "OFFLINE", "BROKEN", "TOTAL")
+	for _, capacity := range gopenqa.WorkerCapacityReport(workers) {
+		fmt.Printf("%-30s %6d %6d %8d %8d %6d\n", capacity.WorkerClass, capacity.Idle, capacity.Busy, capacity.Offline, capacity.Broken, capacity.Total)
+	}
+	return nil
+}
+
+/* Delete offline workers */
+func pruneWorkers(args *Args) error {
+	var offlineSince time.Duration
+	if len(args.Positional) > 0 {
+		return fmt.Errorf("invalid argument: %s", args.Positional[0])
+	}
+	// Deleting all offline workers must be requested explicitly
+	if args.Has("offline-since") == args.Has("all") {
+		return fmt.Errorf("either --offline-since or --all is required")
+	}
+	if args.Has("offline-since") {
+		var err error
+		if offlineSince, err = parseDuration(args.Value("offline-since")); err != nil {
+			return err
+		}
+		if offlineSince <= 0 {
+			return fmt.Errorf("invalid duration: %s", args.Value("offline-since"))
+		}
+	}
+
+	workers, err := instance.GetWorkers()
+	if err != nil {
+		return err
+	}
+	prune := make([]gopenqa.Worker, 0)
+	for _, worker := range workers {
+		if worker.OfflineLongerThan(offlineSince) {
+			prune = append(prune, worker)
+		}
+	}
+	if len(prune) == 0 {
+		fmt.Println("No offline workers to delete")
+		return nil
+	}
+
+	for _, worker := range prune {
+		fmt.Printf("  %s last seen %s\n", worker.String(), worker.Tseen)
+	}
+	if !cf.NoPrompt {
+		fmt.Printf("Are you sure you want to delete %d offline workers?\n", len(prune))
+		if prompt("Type uppercase 'yes' to continue: ") != "YES" {
+			return fmt.Errorf("cancelled")
+		}
+	}
+	for i, worker := range prune {
+		if err := instance.DeleteWorker(worker.ID); err != nil {
+			return err
+		}
+		fmt.Printf("[%d/%d] Deleted worker %s\n", i+1, len(prune), worker.String())
+	}
+	return nil
+}
+
+/* Parse the worker IDs of the arguments */
+func workerIDs(args []string) ([]int, error) {
+	ids, rem := extractIntegers(args)
+	if len(rem) > 0 {
+		return ids, fmt.Errorf("invalid worker id: %s", rem[0])
+	}
+	if len(ids) == 0 {
+		return ids, fmt.Errorf("missing worker ids")
+	}
+	return ids, nil
+}
+
+func getWorkers(args *Args) error {
+	ids, err := workerIDs(args.Positional)
+	if err != nil {
+		return err
+	}
+	for _, id := range ids {
+		if worker, err := instance.GetWorker(id); err != nil {
+			return err
+		} else {
+			if err := printData(worker); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func workerJobs(args *Args) error {
+	ids, err := workerIDs(args.Positional)
+	if err != nil {
+		return err
+	}
+	for _, id := range ids {
+		worker, err := instance.GetWorker(id)
+		if err != nil {
+			return err
+		}
+		if worker.JobID == 0 {
+			fmt.Printf("%s - idle\n", worker.String())
+			continue
+		}
+		job, err := worker.FetchJob()
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%s - %s %s\n", worker.String(), job.String(), job.Link)
+	}
+	return nil
+}
+
+func deleteWorkers(args *Args) error {
+	ids, err := workerIDs(args.Positional)
+	if err != nil {
+		return err
+	}
+	for _, id := range ids {
+		if err := instance.DeleteWorker(id); err != nil {
+			return err
+		}
+		fmt.Printf("Deleted worker %d\n", id)
+	}
+	return nil
+}
+
+func workerCommand(args *Args) error {
+	n := len(args.Positional)
+	if n < 2 {
+		return fmt.Errorf("missing command")
+	}
+	ids, err := workerIDs(args.Positional[:n-1])
+	if err != nil {
+		return err
+	}
+	command := strings.ToLower(args.Positional[n-1])
+	for _, id := range ids {
+		if err := instance.SendWorkerCommand(id, command); err != nil {
+			return err
+		}
+		fmt.Printf("Sent %s to worker %d\n", command, id)
+	}
+	return nil
+}
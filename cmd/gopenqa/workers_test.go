@@ -0,0 +1,66 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/os-autoinst/gopenqa"
+	"github.com/os-autoinst/gopenqa/gopenqatest"
+	"gotest.tools/assert"
+)
+
+// fakeServer starts a fake openQA server and uses it as instance of the commands, without prompts
+func fakeServer(t *testing.T) *gopenqatest.Server {
+	t.Helper()
+	server := gopenqatest.NewServer()
+	noPrompt := cf.NoPrompt
+	instance = gopenqa.CreateInstance(server.URL)
+	instance.SetApiKey(gopenqatest.DefaultAPIKey, gopenqatest.DefaultAPISecret)
+	cf.NoPrompt = true
+	t.Cleanup(func() {
+		instance, cf.NoPrompt = gopenqa.Instance{}, noPrompt
+		server.Close()
+	})
+	return server
+}
+
+func TestPruneWorkers(t *testing.T) {
+	seen := time.Now().Add(-48 * time.Hour).UTC().Format("2006-01-02T15:04:05")
+	tests := []struct {
+		name   string
+		args   []string
+		pruned []int // IDs of the deleted workers
+		err    string
+	}{
+		{"filter required", []string{}, nil, "either --offline-since or --all is required"},
+		{"filters are exclusive", []string{"--all", "--offline-since", "1d"}, nil, "either --offline-since or --all is required"},
+		{"zero duration", []string{"--offline-since", "0"}, nil, "invalid duration: 0"},
+		{"invalid duration", []string{"--offline-since", "soon"}, nil, `time: invalid duration "soon"`},
+		{"stray argument", []string{"--all", "1"}, nil, "invalid argument: 1"},
+		{"offline since", []string{"--offline-since", "1d"}, []int{2}, ""},
+		{"offline since longer", []string{"--offline-since", "3d"}, []int{}, ""},
+		{"all", []string{"--all"}, []int{2, 3}, ""},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			server := fakeServer(t)
+			server.AddWorker(gopenqa.Worker{ID: 1, Host: "busy", Status: "running", Tseen: seen})
+			server.AddWorker(gopenqa.Worker{ID: 2, Host: "dead", Status: "dead", Tseen: seen})
+			server.AddWorker(gopenqa.Worker{ID: 3, Host: "never seen", Status: "dead"})
+			_, args, err := parseCommand(commands(), append([]string{"workers", "prune"}, test.args...))
+			assert.NilError(t, err)
+			if test.err != "" {
+				assert.Error(t, pruneWorkers(args), test.err)
+				return
+			}
+			captureStdout(t, func() error { return pruneWorkers(args) })
+			pruned := make([]int, 0)
+			for _, id := range []int{1, 2, 3} {
+				if _, ok := server.Worker(id); !ok {
+					pruned = append(pruned, id)
+				}
+			}
+			assert.DeepEqual(t, pruned, test.pruned)
+		})
+	}
+}
@@ -0,0 +1,332 @@
+package gopenqa
+
+import (
+	"fmt"
+	"sort"
+)
+
+/* Configuration holds the test configuration of an openQA instance, i.e. everything needed to recreate it on another instance */
+type Configuration struct {
+	Machines       []Machine      `json:"machines"`
+	Products       []Product      `json:"products"`
+	TestSuites     []TestSuite    `json:"test_suites"`
+	ParentGroups   []JobGroup     `json:"parent_groups"`
+	JobGroups      []JobGroup     `json:"job_groups"`
+	SchedulingYAML map[int]string `json:"scheduling_yaml"` // Job templates scheduling YAML per job group ID
+}
+
+/* Options for importing a configuration into an instance */
+type ImportOptions struct {
+	Update    bool  // Update existing entities. Otherwise existing entities are left untouched
+	JobGroups []int // Only import the given job groups (by their ID in the configuration) and their parent groups. Empty imports all
+}
+
+// Import actions for a single entity
+const (
+	ImportCreated = "created"
+	ImportUpdated = "updated"
+	ImportSkipped = "skipped"
+)
+
+/* Outcome of importing a single entity */
+type ImportItem struct {
+	Entity   string `json:"entity"` // "machine", "product", "test_suite", "parent_group", "job_group" or "scheduling"
+	Name     string `json:"name"`
+	SourceID int    `json:"source_id"` // ID in the configuration
+	ID       int    `json:"id"`        // ID on the destination instance
+	Action   string `json:"action"`
+}
+
+/* ImportReport lists all imported entities and the mapping of the group IDs from the configuration to the destination instance */
+type ImportReport struct {
+	Items        []ImportItem `json:"items"`
+	ParentGroups map[int]int  `json:"parent_groups"`
+	JobGroups    map[int]int  `json:"job_groups"`
+}
+
+func (r *ImportReport) add(entity string, name string, sourceID int, id int, action string) {
+	r.Items = append(r.Items, ImportItem{Entity: entity, Name: name, SourceID: sourceID, ID: id, Action: action})
+}
+
+/* Count returns the number of entities with the given action */
+func (r *ImportReport) Count(action string) int {
+	n := 0
+	for _, item := range r.Items {
+		if item.Action == action {
+			n++
+		}
+	}
+	return n
+}
+
+func productKey(p Product) string {
+	return fmt.Sprintf("%s-%s-%s-%s", p.Distri, p.Version, p.Flavor, p.Arch)
+}
+
+func equalSettings(a map[string]string, b map[string]string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for k, v := range a {
+		if v2, ok := b[k]; !ok || v != v2 {
+			return false
+		}
+	}
+	return true
+}
+
+// ExportConfiguration fetches the test configuration of the instance
+func (i *Instance) ExportConfiguration() (Configuration, error) {
+	var cf Configuration
+	var err error
+	if cf.Machines, err = i.GetMachines(); err != nil {
+		return cf, fmt.Errorf("machines: %s", err)
+	}
+	if cf.Products, err = i.GetProducts(); err != nil {
+		return cf, fmt.Errorf("products: %s", err)
+	}
+	if cf.TestSuites, err = i.GetTestSuites(); err != nil {
+		return cf, fmt.Errorf("test suites: %s", err)
+	}
+	if cf.ParentGroups, err = i.GetParentJobGroups(); err != nil {
+		return cf, fmt.Errorf("parent groups: %s", err)
+	}
+	if cf.JobGroups, err = i.GetJobGroups(); err != nil {
+		return cf, fmt.Errorf("job groups: %s", err)
+	}
+	cf.SchedulingYAML = make(map[int]string, 0)
+	for _, group := range cf.JobGroups {
+		yaml, err := i.GetJobTemplateYAML(group.ID)
+		if err != nil {
+			return cf, fmt.Errorf("scheduling of job group %d: %s", group.ID, err)
+		}
+		cf.SchedulingYAML[group.ID] = yaml
+	}
+	return cf, nil
+}
+
+// selectGroups returns the job groups and parent groups to import according to the options
+func (cf *Configuration) selectGroups(opts ImportOptions) ([]JobGroup, []JobGroup) {
+	if len(opts.JobGroups) == 0 {
+		return cf.JobGroups, cf.ParentGroups
+	}
+	groups := make([]JobGroup, 0)
+	parents := make(map[int]bool, 0)
+	for _, group := range cf.JobGroups {
+		for _, id := range opts.JobGroups {
+			if group.ID == id {
+				groups = append(groups, group)
+				parents[group.ParentID] = true
+			}
+		}
+	}
+	parentGroups := make([]JobGroup, 0)
+	for _, parent := range cf.ParentGroups {
+		if parents[parent.ID] {
+			parentGroups = append(parentGroups, parent)
+		}
+	}
+	return groups, parentGroups
+}
+
+// ImportConfiguration creates the given configuration on the instance. Entities are matched by their name (products by distri, version, flavor and arch)
+// Existing entities are updated if opts.Update is set and skipped otherwise. Parent group references are remapped to the IDs of the instance
+// The import stops at the first error and returns the report of all entities imported so far
+func (i *Instance) ImportConfiguration(cf Configuration, opts ImportOptions) (ImportReport, error) {
+	report := ImportReport{Items: make([]ImportItem, 0), ParentGroups: make(map[int]int, 0), JobGroups: make(map[int]int, 0)}
+	jobGroups, parentGroups := cf.selectGroups(opts)
+
+	// Machines, products and test suites first, as the scheduling YAML refers to them
+	machines, err := i.GetMachines()
+	if err != nil {
+		return report, err
+	}
+	for _, machine := range cf.Machines {
+		sourceID := machine.ID
+		action := ImportCreated
+		machine.ID = 0
+		for _, existing := range machines {
+			if existing.Name == machine.Name {
+				machine.ID = existing.ID
+				action = ImportSkipped
+				if opts.Update && (existing.Backend != machine.Backend || !equalSettings(existing.Settings, machine.Settings)) {
+					action = ImportUpdated
+				}
+			}
+		}
+		if action != ImportSkipped {
+			posted, err := i.PostMachine(machine)
+			if err != nil {
+				return report, fmt.Errorf("machine %s: %s", machine.Name, err)
+			}
+			if machine.ID == 0 {
+				machine.ID = posted.ID
+			}
+		}
+		report.add("machine", machine.Name, sourceID, machine.ID, action)
+	}
+
+	products, err := i.GetProducts()
+	if err != nil {
+		return report, err
+	}
+	for _, product := range cf.Products {
+		sourceID := product.ID
+		action := ImportCreated
+		product.ID = 0
+		for _, existing := range products {
+			if productKey(existing) == productKey(product) {
+				product.ID = existing.ID
+				action = ImportSkipped
+				if opts.Update && !equalSettings(existing.Settings, product.Settings) {
+					action = ImportUpdated
+				}
+			}
+		}
+		if action != ImportSkipped {
+			posted, err := i.PostProduct(product)
+			if err != nil {
+				return report, fmt.Errorf("product %s: %s", productKey(product), err)
+			}
+			if product.ID == 0 {
+				product.ID = posted.ID
+			}
+		}
+		report.add("product", productKey(product), sourceID, product.ID, action)
+	}
+
+	suites, err := i.GetTestSuites()
+	if err != nil {
+		return report, err
+	}
+	for _, suite := range cf.TestSuites {
+		sourceID := suite.ID
+		action := ImportCreated
+		suite.ID = 0
+		for _, existing := range suites {
+			if existing.Name == suite.Name {
+				suite.ID = existing.ID
+				action = ImportSkipped
+				if opts.Update && (existing.Description != suite.Description || !equalSettings(existing.Settings, suite.Settings)) {
+					action = ImportUpdated
+				}
+			}
+		}
+		if action != ImportSkipped {
+			posted, err := i.PostTestSuite(suite)
+			if err != nil {
+				return report, fmt.Errorf("test suite %s: %s", suite.Name, err)
+			}
+			if suite.ID == 0 {
+				suite.ID = posted.ID
+			}
+		}
+		report.add("test_suite", suite.Name, sourceID, suite.ID, action)
+	}
+
+	// Parent groups before job groups, so that the parent IDs can be remapped
+	existingParents, err := i.GetParentJobGroups()
+	if err != nil {
+		return report, err
+	}
+	for _, group := range parentGroups {
+		sourceID := group.ID
+		id, action, err := i.importGroup(group, existingParents, opts.Update, true)
+		if err != nil {
+			return report, fmt.Errorf("parent group %s: %s", group.Name, err)
+		}
+		report.ParentGroups[sourceID] = id
+		report.add("parent_group", group.Name, sourceID, id, action)
+	}
+
+	existingGroups, err := i.GetJobGroups()
+	if err != nil {
+		return report, err
+	}
+	for _, group := range jobGroups {
+		sourceID := group.ID
+		if group.ParentID != 0 {
+			parentID, ok := report.ParentGroups[group.ParentID]
+			if !ok {
+				return report, fmt.Errorf("job group %s: parent group %d not found", group.Name, group.ParentID)
+			}
+			group.ParentID = parentID
+		}
+		id, action, err := i.importGroup(group, existingGroups, opts.Update, false)
+		if err != nil {
+			return report, fmt.Errorf("job group %s: %s", group.Name, err)
+		}
+		report.JobGroups[sourceID] = id
+		report.add("job_group", group.Name, sourceID, id, action)
+	}
+
+	// Scheduling YAML last, as it refers to all other entities. Ordered by group ID for a deterministic report
+	sourceIDs := make([]int, 0)
+	for sourceID := range cf.SchedulingYAML {
+		if _, ok := report.JobGroups[sourceID]; ok {
+			sourceIDs = append(sourceIDs, sourceID)
+		}
+	}
+	sort.Ints(sourceIDs)
+	for _, sourceID := range sourceIDs {
+		yaml := cf.SchedulingYAML[sourceID]
+		id := report.JobGroups[sourceID]
+		existing, err := i.GetJobTemplateYAML(id)
+		if err != nil {
+			return report, fmt.Errorf("scheduling of job group %d: %s", id, err)
+		}
+		action := ImportCreated
+		if existing == yaml || yaml == "" {
+			action = ImportSkipped
+		} else if existing != "" {
+			action = ImportSkipped
+			if opts.Update {
+				action = ImportUpdated
+			}
+		}
+		if action != ImportSkipped {
+			if err := i.PostJobTemplateYAML(id, yaml); err != nil {
+				return report, fmt.Errorf("scheduling of job group %d: %s", id, err)
+			}
+		}
+		report.add("scheduling", fmt.Sprintf("%d", id), sourceID, id, action)
+	}
+	return report, nil
+}
+
+// importGroup creates or updates the given (parent) job group. Returns the ID on the instance and the performed action
+func (i *Instance) importGroup(group JobGroup, existing []JobGroup, update bool, parent bool) (int, string, error) {
+	group.ID = 0
+	for _, e := range existing {
+		if e.Name == group.Name {
+			group.ID = e.ID
+		}
+	}
+	var err error
+	if group.ID == 0 {
+		if parent {
+			group, err = i.PostParentJobGroup(group)
+		} else {
+			group, err = i.PostJobGroup(group)
+		}
+		return group.ID, ImportCreated, err
+	}
+	if !update {
+		return group.ID, ImportSkipped, nil
+	}
+	if parent {
+		_, err = i.UpdateParentJobGroup(group)
+	} else {
+		_, err = i.UpdateJobGroup(group)
+	}
+	return group.ID, ImportUpdated, err
+}
+
+// CopyConfiguration copies the test configuration from the source to the destination instance. See ImportConfiguration
+func CopyConfiguration(src *Instance, dst *Instance, opts ImportOptions) (ImportReport, error) {
+	cf, err := src.ExportConfiguration()
+	if err != nil {
+		return ImportReport{}, err
+	}
+	return dst.ImportConfiguration(cf, opts)
+}
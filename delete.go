@@ -0,0 +1,302 @@
+package gopenqa
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+/* DeleteFilter selects the items of a bulk deletion. Empty fields match all items */
+type DeleteFilter struct {
+	Name      string        // Regular expression for the name
+	MinID     int64         // Lowest ID, 0 for no lower bound
+	MaxID     int64         // Highest ID, 0 for no upper bound
+	OlderThan time.Duration // Only items without activity within the given duration. For job groups, this is the newest job of the group
+	Unused    bool          // Only items that are not used by any job template
+}
+
+/* Options of bulk deletions */
+type DeleteOptions struct {
+	DryRun          bool                        // Only report what would be deleted
+	ContinueOnError bool                        // Continue with the remaining items if a deletion fails
+	Progress        func(outcome DeleteOutcome) // Called after each item, if set
+}
+
+/* Outcome of a single item of a bulk deletion */
+type DeleteOutcome struct {
+	ID      int64
+	Name    string
+	Deleted bool  // false for dry runs, failed and skipped items
+	Skipped bool  // Not attempted because a previous deletion failed
+	Err     error // Error of the deletion, if failed
+}
+
+func (o DeleteOutcome) String() string {
+	if o.Name == "" {
+		return fmt.Sprintf("%d", o.ID)
+	}
+	return fmt.Sprintf("%d %s", o.ID, o.Name)
+}
+
+/* Outcomes of a bulk deletion, in the order of the items */
+type DeleteReport []DeleteOutcome
+
+// Deleted returns the number of deleted items
+func (r DeleteReport) Deleted() int {
+	count := 0
+	for _, outcome := range r {
+		if outcome.Deleted {
+			count++
+		}
+	}
+	return count
+}
+
+// Failed returns the number of items that could not be deleted
+func (r DeleteReport) Failed() int {
+	count := 0
+	for _, outcome := range r {
+		if outcome.Err != nil {
+			count++
+		}
+	}
+	return count
+}
+
+// Skipped returns the number of items that were not attempted because of a previous failure
+func (r DeleteReport) Skipped() int {
+	count := 0
+	for _, outcome := range r {
+		if outcome.Skipped {
+			count++
+		}
+	}
+	return count
+}
+
+// Err returns nil if no deletion failed, otherwise an error containing the first failure
+func (r DeleteReport) Err() error {
+	for _, outcome := range r {
+		if outcome.Err != nil {
+			if failed := r.Failed(); failed > 1 {
+				return fmt.Errorf("%d of %d deletions failed, first %s: %s", failed, len(r), outcome.String(), outcome.Err)
+			}
+			return fmt.Errorf("%s: %s", outcome.String(), outcome.Err)
+		}
+	}
+	return nil
+}
+
+// matcher returns a function matching the ID and name of an item against the filter
+func (f *DeleteFilter) matcher() (func(id int64, name string) bool, error) {
+	var name *regexp.Regexp
+	if f.Name != "" {
+		var err error
+		if name, err = regexp.Compile(f.Name); err != nil {
+			return nil, fmt.Errorf("invalid name filter: %s", err)
+		}
+	}
+	return func(id int64, n string) bool {
+		if f.MinID > 0 && id < f.MinID {
+			return false
+		}
+		if f.MaxID > 0 && id > f.MaxID {
+			return false
+		}
+		return name == nil || name.MatchString(n)
+	}, nil
+}
+
+// bulkDelete deletes the given items one by one and collects the outcomes
+func bulkDelete(items []DeleteOutcome, del func(item DeleteOutcome) error, options DeleteOptions) DeleteReport {
+	report := make(DeleteReport, 0, len(items))
+	failed := false
+	for _, outcome := range items {
+		if failed && !options.ContinueOnError {
+			outcome.Skipped = true
+		} else if !options.DryRun {
+			if outcome.Err = del(outcome); outcome.Err != nil {
+				failed = true
+			} else {
+				outcome.Deleted = true
+			}
+		}
+		report = append(report, outcome)
+		if options.Progress != nil {
+			options.Progress(outcome)
+		}
+	}
+	return report
+}
+
+// jobActivity returns the time of the last activity of the given job. Scheduled and running jobs are active now
+func jobActivity(job Job) (time.Time, error) {
+	if job.State == "scheduled" || job.State == "running" || job.State == "assigned" || job.State == "setup" || job.State == "uploading" {
+		return time.Now(), nil
+	}
+	timestamp := job.Tfinished
+	if timestamp == "" {
+		timestamp = job.Tstarted
+	}
+	if timestamp == "" {
+		return time.Time{}, fmt.Errorf("unknown")
+	}
+	// openQA returns UTC timestamps, sometimes without the trailing Z
+	return time.Parse(time.RFC3339, strings.TrimSuffix(timestamp, "Z")+"Z")
+}
+
+/* DeleteJobs deletes the given jobs */
+func (i *Instance) DeleteJobs(ids []int64, options DeleteOptions) DeleteReport {
+	items := make([]DeleteOutcome, 0)
+	for _, id := range ids {
+		items = append(items, DeleteOutcome{ID: id})
+	}
+	return bulkDelete(items, func(item DeleteOutcome) error { return i.DeleteJob(item.ID) }, options)
+}
+
+/* DeleteJobGroupJobsWithOptions deletes all jobs of the given job group. Returns an error if the jobs cannot be fetched */
+func (i *Instance) DeleteJobGroupJobsWithOptions(id int, options DeleteOptions) (DeleteReport, error) {
+	jobs, err := i.GetJobGroupJobs(id)
+	if err != nil {
+		return DeleteReport{}, err
+	}
+	return i.DeleteJobs(jobs, options), nil
+}
+
+/* FilterJobGroups returns the job groups matching the given filter */
+func (i *Instance) FilterJobGroups(filter DeleteFilter) ([]JobGroup, error) {
+	ret := make([]JobGroup, 0)
+	match, err := filter.matcher()
+	if err != nil {
+		return ret, err
+	}
+	groups, err := i.GetJobGroups()
+	if err != nil {
+		return ret, err
+	}
+	used := make(map[string]bool, 0)
+	if filter.Unused {
+		templates, err := i.GetJobTemplates()
+		if err != nil {
+			return ret, err
+		}
+		for _, template := range templates {
+			used[template.GroupName] = true
+		}
+	}
+	for _, group := range groups {
+		if !match(int64(group.ID), group.Name) || used[group.Name] {
+			continue
+		}
+		if filter.OlderThan > 0 {
+			active, err := i.jobGroupActiveSince(group.ID, time.Now().Add(-filter.OlderThan))
+			if err != nil {
+				return ret, err
+			}
+			if active {
+				continue
+			}
+		}
+		ret = append(ret, group)
+	}
+	return ret, nil
+}
+
+// jobGroupActiveSince returns true if the newest job of the given job group was active after the given time
+func (i *Instance) jobGroupActiveSince(id int, since time.Time) (bool, error) {
+	jobs, err := i.GetJobGroupJobs(id)
+	if err != nil || len(jobs) == 0 {
+		return false, err
+	}
+	newest := jobs[0]
+	for _, job := range jobs {
+		if job > newest {
+			newest = job
+		}
+	}
+	job, err := i.GetJob(newest)
+	if err != nil {
+		return false, err
+	}
+	activity, err := jobActivity(job)
+	if err != nil {
+		// Be conservative with jobs of unknown age
+		return true, nil
+	}
+	return activity.After(since), nil
+}
+
+/* DeleteJobGroups deletes the given job groups including their job templates and jobs */
+func (i *Instance) DeleteJobGroups(groups []JobGroup, options DeleteOptions) DeleteReport {
+	items := make([]DeleteOutcome, 0)
+	for _, group := range groups {
+		items = append(items, DeleteOutcome{ID: int64(group.ID), Name: group.Name})
+	}
+	var templates []JobTemplate
+	return bulkDelete(items, func(item DeleteOutcome) error {
+		if templates == nil {
+			var err error
+			if templates, err = i.GetJobTemplates(); err != nil {
+				templates = nil
+				return err
+			}
+		}
+		for _, template := range templates {
+			if template.GroupName == item.Name {
+				if err := i.DeleteJobTemplate(template.ID); err != nil {
+					return fmt.Errorf("job template %d: %s", template.ID, err)
+				}
+			}
+		}
+		report, err := i.DeleteJobGroupJobsWithOptions(int(item.ID), DeleteOptions{ContinueOnError: options.ContinueOnError})
+		if err != nil {
+			return err
+		}
+		if err := report.Err(); err != nil {
+			return fmt.Errorf("deleting jobs: %s", err)
+		}
+		return i.DeleteJobGroup(int(item.ID))
+	}, options)
+}
+
+/* FilterMachines returns the machines matching the given filter. openQA does not track the age of machines, so OlderThan is not supported */
+func (i *Instance) FilterMachines(filter DeleteFilter) ([]Machine, error) {
+	ret := make([]Machine, 0)
+	if filter.OlderThan > 0 {
+		return ret, fmt.Errorf("age filter is not supported for machines")
+	}
+	match, err := filter.matcher()
+	if err != nil {
+		return ret, err
+	}
+	machines, err := i.GetMachines()
+	if err != nil {
+		return ret, err
+	}
+	used := make(map[int]bool, 0)
+	if filter.Unused {
+		templates, err := i.GetJobTemplates()
+		if err != nil {
+			return ret, err
+		}
+		for _, template := range templates {
+			used[template.Machine.ID] = true
+		}
+	}
+	for _, machine := range machines {
+		if match(int64(machine.ID), machine.Name) && !used[machine.ID] {
+			ret = append(ret, machine)
+		}
+	}
+	return ret, nil
+}
+
+/* DeleteMachines deletes the given machines */
+func (i *Instance) DeleteMachines(machines []Machine, options DeleteOptions) DeleteReport {
+	items := make([]DeleteOutcome, 0)
+	for _, machine := range machines {
+		items = append(items, DeleteOutcome{ID: int64(machine.ID), Name: machine.Name})
+	}
+	return bulkDelete(items, func(item DeleteOutcome) error { return i.DeleteMachine(int(item.ID)) }, options)
+}
@@ -0,0 +1,371 @@
+package gopenqa
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// Event types as published by openQA. The type is the routing key without the "<prefix>.openqa." part
+const (
+	EventJobCreate         = "job.create"
+	EventJobDelete         = "job.delete"
+	EventJobCancel         = "job.cancel"
+	EventJobDuplicate      = "job.duplicate"
+	EventJobRestart        = "job.restart"
+	EventJobUpdateResult   = "job.update_result"
+	EventJobDone           = "job.done"
+	EventCommentCreate     = "comment.create"
+	EventCommentUpdate     = "comment.update"
+	EventCommentDelete     = "comment.delete"
+	EventISOCreate         = "iso.create"
+	EventISODelete         = "iso.delete"
+	EventISOCancel         = "iso.cancel"
+	EventJobGroupCreate    = "jobgroup.create"
+	EventJobGroupUpdate    = "jobgroup.update"
+	EventJobGroupDelete    = "jobgroup.delete"
+	EventJobGroupConnect   = "jobgroup.connect"
+	EventParentGroupCreate = "parentgroup.create"
+	EventParentGroupUpdate = "parentgroup.update"
+	EventParentGroupDelete = "parentgroup.delete"
+	EventWorkerRegister    = "worker.register"
+	EventWorkerDelete      = "worker.delete"
+	EventJobTemplateCreate = "jobtemplate.create"
+	EventJobTemplateDelete = "jobtemplate.delete"
+	EventTableCreate       = "table.create"
+	EventTableUpdate       = "table.update"
+	EventTableDelete       = "table.delete"
+)
+
+/* Event is a single message received from RabbitMQ. Depending on the type, one of the payload fields is set */
+type Event struct {
+	Key      string          `json:"key"`  // Routing key of the message
+	Type     string          `json:"type"` // Event type, e.g. "job.done"
+	Raw      json.RawMessage `json:"raw"`  // Raw payload, also for unknown event types
+	Job      *JobStatus      `json:"job,omitempty"`
+	Comment  *CommentMQ      `json:"comment,omitempty"`
+	ISO      *ISOEvent       `json:"iso,omitempty"`
+	JobGroup *JobGroupEvent  `json:"job_group,omitempty"` // Job group and parent group events
+	Worker   *WorkerEvent    `json:"worker,omitempty"`
+	delivery *amqp.Delivery  // Original message, if it needs to be acknowledged
+	instance *Instance       // Instance for fetching further information, if the subscription is bound to one
+	cache    *eventCache     // Lazily fetched information, shared between copies of the event
+}
+
+// eventCache holds the information fetched for an event, so that it is fetched only once
+type eventCache struct {
+	job      *Job
+	jobGroup *JobGroup
+	comments []Comment
+}
+
+/* Payload of iso.* events, i.e. scheduled products */
+type ISOEvent struct {
+	ID                 int64             `json:"id"`
+	ScheduledProductID int64             `json:"scheduled_product_id"`
+	Distri             string            `json:"DISTRI"`
+	Version            string            `json:"VERSION"`
+	Flavor             string            `json:"FLAVOR"`
+	Arch               string            `json:"ARCH"`
+	Build              string            `json:"BUILD"`
+	ISO                string            `json:"ISO"`
+	Settings           map[string]string `json:"-"` // All string settings of the payload
+}
+
+/* Payload of job group and parent group events */
+type JobGroupEvent struct {
+	ID       int    `json:"id"`
+	Name     string `json:"name"`
+	ParentID int    `json:"parent_id"`
+	Parent   bool   `json:"-"` // true, if the event refers to a parent group
+}
+
+/* Payload of worker events */
+type WorkerEvent struct {
+	ID       int    `json:"id"`
+	Host     string `json:"host"`
+	Instance int    `json:"instance"`
+	Status   string `json:"status"`
+	JobID    int64  `json:"job_id"`
+}
+
+// EventType derives the event type from the given routing key, e.g. "suse.openqa.job.done" becomes "job.done"
+func EventType(key string) string {
+	if i := strings.Index(key, ".openqa."); i >= 0 {
+		return key[i+len(".openqa."):]
+	}
+	if strings.HasPrefix(key, "openqa.") {
+		return key[len("openqa."):]
+	}
+	// Fallback: Use the last two components
+	parts := strings.Split(key, ".")
+	if len(parts) > 2 {
+		parts = parts[len(parts)-2:]
+	}
+	return strings.Join(parts, ".")
+}
+
+/* Category returns the entity the event refers to, e.g. "job" for "job.done" */
+func (e *Event) Category() string {
+	if i := strings.Index(e.Type, "."); i >= 0 {
+		return e.Type[:i]
+	}
+	return e.Type
+}
+
+/* Action returns the action of the event, e.g. "done" for "job.done" */
+func (e *Event) Action() string {
+	if i := strings.Index(e.Type, "."); i >= 0 {
+		return e.Type[i+1:]
+	}
+	return ""
+}
+
+/* Known returns true, if the payload of the event has been parsed */
+func (e *Event) Known() bool {
+	return e.Job != nil || e.Comment != nil || e.ISO != nil || e.JobGroup != nil || e.Worker != nil
+}
+
+/* Ack acknowledges the event. This is only required for subscriptions with ManualAck */
+func (e *Event) Ack() error {
+	if e.delivery == nil {
+		return nil
+	}
+	return e.delivery.Ack(false)
+}
+
+/* Nack rejects the event. If requeue is true, the event will be delivered again. This is only required for subscriptions with ManualAck */
+func (e *Event) Nack(requeue bool) error {
+	if e.delivery == nil {
+		return nil
+	}
+	return e.delivery.Nack(false, requeue)
+}
+
+/* Format event as a string */
+func (e *Event) String() string {
+	return e.Type + " " + string(e.Raw)
+}
+
+// parseEvent parses the given message according to its routing key. For unknown types only the raw payload is set
+// If the payload of a known type cannot be parsed, the event is returned together with the error
+func parseEvent(d amqp.Delivery) (Event, error) {
+	event := Event{Key: d.RoutingKey, Type: EventType(d.RoutingKey), Raw: json.RawMessage(d.Body), cache: &eventCache{}}
+	switch event.Category() {
+	case "job":
+		status, err := parseJobStatus(d)
+		if err != nil {
+			return event, err
+		}
+		event.Job = &status
+	case "comment":
+		var comment CommentMQ
+		if err := json.Unmarshal(d.Body, &comment); err != nil {
+			return event, err
+		}
+		event.Comment = &comment
+	case "iso":
+		var iso ISOEvent
+		if err := json.Unmarshal(d.Body, &iso); err != nil {
+			return event, err
+		}
+		// Settings are passed as top-level values
+		var values map[string]interface{}
+		if err := json.Unmarshal(d.Body, &values); err != nil {
+			return event, err
+		}
+		iso.Settings = make(map[string]string, 0)
+		for k, v := range values {
+			if s, ok := v.(string); ok {
+				iso.Settings[k] = s
+			}
+		}
+		event.ISO = &iso
+	case "jobgroup", "job_group", "parentgroup", "parent_group":
+		var group JobGroupEvent
+		if err := json.Unmarshal(d.Body, &group); err != nil {
+			return event, err
+		}
+		group.Parent = strings.HasPrefix(event.Type, "parent")
+		event.JobGroup = &group
+	case "worker":
+		var worker WorkerEvent
+		if err := json.Unmarshal(d.Body, &worker); err != nil {
+			return event, err
+		}
+		event.Worker = &worker
+	}
+	return event, nil
+}
+
+/* GroupID returns the job group ID the event refers to, or 0 if not present */
+func (e *Event) GroupID() int {
+	if e.Job != nil {
+		return e.Job.GroupID
+	}
+	if e.Comment != nil {
+		return e.Comment.GroupID
+	}
+	if e.JobGroup != nil && !e.JobGroup.Parent {
+		return e.JobGroup.ID
+	}
+	return 0
+}
+
+/* Build returns the build the event refers to, or an empty string if not present */
+func (e *Event) Build() string {
+	if e.Job != nil {
+		return e.Job.Build
+	}
+	if e.ISO != nil {
+		return e.ISO.Build
+	}
+	return ""
+}
+
+/* Test returns the test name the event refers to, or an empty string if not present */
+func (e *Event) Test() string {
+	if e.Job != nil {
+		return e.Job.Test
+	}
+	return ""
+}
+
+/* Arch returns the architecture the event refers to, or an empty string if not present */
+func (e *Event) Arch() string {
+	if e.Job != nil {
+		return e.Job.Arch
+	}
+	if e.ISO != nil {
+		return e.ISO.Arch
+	}
+	return ""
+}
+
+/* Flavor returns the flavor the event refers to, or an empty string if not present */
+func (e *Event) Flavor() string {
+	if e.Job != nil {
+		return e.Job.Flavor
+	}
+	if e.ISO != nil {
+		return e.ISO.Flavor
+	}
+	return ""
+}
+
+/* Result returns the job result of the event, or an empty string if not present. Results of restarted jobs are not plain results and are ignored */
+func (e *Event) Result() string {
+	if e.Job != nil {
+		if result, ok := e.Job.Result.(string); ok {
+			return result
+		}
+	}
+	return ""
+}
+
+/* JobID returns the ID of the job the event refers to, or 0 if not present */
+func (e *Event) JobID() int64 {
+	if e.Job != nil {
+		return e.Job.ID
+	}
+	if e.Comment != nil {
+		return e.Comment.JobID
+	}
+	if e.Worker != nil {
+		return e.Worker.JobID
+	}
+	return 0
+}
+
+/* Link returns the link to the job of the event, if the event is bound to an instance and refers to a job */
+func (e *Event) Link() string {
+	if e.instance == nil || e.JobID() == 0 {
+		return ""
+	}
+	return fmt.Sprintf("%s/tests/%d", e.instance.URL, e.JobID())
+}
+
+/* SetInstance binds the event to the given instance, so that further information can be fetched */
+func (e *Event) SetInstance(instance *Instance) {
+	e.instance = instance
+}
+
+func (e *Event) checkInstance() error {
+	if e.instance == nil {
+		return fmt.Errorf("no instance assigned")
+	}
+	if e.cache == nil {
+		e.cache = &eventCache{}
+	}
+	return nil
+}
+
+/* FetchJob fetches the full job the event refers to. The job is only fetched once per event */
+func (e *Event) FetchJob() (Job, error) {
+	if err := e.checkInstance(); err != nil {
+		return Job{}, err
+	}
+	if e.cache.job != nil {
+		return *e.cache.job, nil
+	}
+	id := e.JobID()
+	if id == 0 {
+		return Job{}, fmt.Errorf("event refers to no job")
+	}
+	job, err := e.instance.GetJob(id)
+	if err != nil {
+		return job, err
+	}
+	e.cache.job = &job
+	return job, nil
+}
+
+/* FetchJobGroup fetches the job group the event refers to, e.g. to get the group name. The job group is only fetched once per event */
+func (e *Event) FetchJobGroup() (JobGroup, error) {
+	if err := e.checkInstance(); err != nil {
+		return JobGroup{}, err
+	}
+	if e.cache.jobGroup != nil {
+		return *e.cache.jobGroup, nil
+	}
+	id := e.GroupID()
+	if id == 0 && e.JobID() != 0 {
+		// e.g. comments only refer to the job
+		job, err := e.FetchJob()
+		if err != nil {
+			return JobGroup{}, err
+		}
+		id = job.GroupID
+	}
+	if id == 0 {
+		return JobGroup{}, fmt.Errorf("event refers to no job group")
+	}
+	group, err := e.instance.GetJobGroup(id)
+	if err != nil {
+		return group, err
+	}
+	e.cache.jobGroup = &group
+	return group, nil
+}
+
+/* FetchComments fetches the comments of the job the event refers to. The comments are only fetched once per event */
+func (e *Event) FetchComments() ([]Comment, error) {
+	if err := e.checkInstance(); err != nil {
+		return make([]Comment, 0), err
+	}
+	if e.cache.comments != nil {
+		return e.cache.comments, nil
+	}
+	id := e.JobID()
+	if id == 0 {
+		return make([]Comment, 0), fmt.Errorf("event refers to no job")
+	}
+	comments, err := e.instance.GetComments(id)
+	if err != nil {
+		return comments, err
+	}
+	e.cache.comments = comments
+	return comments, nil
+}
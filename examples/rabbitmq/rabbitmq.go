@@ -1,8 +1,10 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"os"
+	"os/signal"
 
 	"github.com/os-autoinst/gopenqa"
 )
@@ -23,11 +25,21 @@ func main() {
 		os.Exit(1)
 	}
 	defer sub.Close()
+	// The subscription reconnects automatically, if the connection is lost
+	sub.NotifyState(func(state gopenqa.RabbitMQState, err error) {
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "RabbitMQ %s: %s\n", state, err)
+		} else {
+			fmt.Fprintf(os.Stderr, "RabbitMQ %s\n", state)
+		}
+	})
 	fmt.Fprintf(os.Stderr, "Connected and subscribed to rabbit.opensuse.org\n")
-	// Receive job updates, this could also happen as background thread
-	for {
-		if job, err := sub.ReceiveJob(); err == nil {
-			fmt.Printf("%s - %s\n", job.String(), job.JobState())
+	// Receive job updates until interrupted. Events can also be consumed within a select statement
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	for event := range sub.Events(ctx) {
+		if event.Job != nil {
+			fmt.Printf("%d %s - %v\n", event.Job.ID, event.Job.Test, event.Job.Result)
 		}
 	}
 }
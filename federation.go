@@ -0,0 +1,110 @@
+package gopenqa
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+/* Federation bundles multiple openQA instances, which are queried concurrently.
+ * Results are merged and tagged with the Remote of the instance they come from */
+type Federation struct {
+	Instances []*Instance
+}
+
+/* InstanceError is the error of a query against a single instance of a federation */
+type InstanceError struct {
+	Remote string
+	Err    error
+}
+
+func (e InstanceError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Remote, e.Err)
+}
+
+func (e InstanceError) Unwrap() error {
+	return e.Err
+}
+
+/* FederationError collects the errors of all failed instances of a federated query.
+ * The results of the other instances are still returned */
+type FederationError []InstanceError
+
+func (e FederationError) Error() string {
+	msgs := make([]string, 0)
+	for _, err := range e {
+		msgs = append(msgs, err.Error())
+	}
+	return strings.Join(msgs, "; ")
+}
+
+/* Create a federation of the given instances */
+func CreateFederation(instances ...*Instance) Federation {
+	return Federation{Instances: instances}
+}
+
+// Add an instance to the federation
+func (f *Federation) Add(instance *Instance) {
+	f.Instances = append(f.Instances, instance)
+}
+
+// Get the instance with the given remote URL, or nil if not present
+func (f *Federation) Instance(remote string) *Instance {
+	for _, instance := range f.Instances {
+		if instance.URL == remote {
+			return instance
+		}
+	}
+	return nil
+}
+
+// federate runs the given query concurrently on all instances. Results are returned in the order of the instances
+// If any instance fails, the results of the others are returned together with a FederationError
+func federate[T any](f *Federation, query func(instance *Instance) ([]T, error)) ([]T, error) {
+	results := make([][]T, len(f.Instances))
+	errs := make([]error, len(f.Instances))
+	var wg sync.WaitGroup
+	for i, instance := range f.Instances {
+		wg.Add(1)
+		go func(i int, instance *Instance) {
+			defer wg.Done()
+			results[i], errs[i] = query(instance)
+		}(i, instance)
+	}
+	wg.Wait()
+
+	ret := make([]T, 0)
+	var ferr FederationError
+	for i, instance := range f.Instances {
+		if errs[i] != nil {
+			ferr = append(ferr, InstanceError{Remote: instance.URL, Err: errs[i]})
+			continue
+		}
+		ret = append(ret, results[i]...)
+	}
+	if len(ferr) > 0 {
+		return ret, ferr
+	}
+	return ret, nil
+}
+
+// GetJobsByBuild fetches the jobs of the given build from all instances. See Instance.GetJobsByBuild
+func (f *Federation) GetJobsByBuild(build string, params map[string]string) ([]Job, error) {
+	return federate(f, func(instance *Instance) ([]Job, error) {
+		return instance.GetJobsByBuild(build, params)
+	})
+}
+
+// GetJobGroups fetches the job groups of all instances
+func (f *Federation) GetJobGroups() ([]JobGroup, error) {
+	return federate(f, func(instance *Instance) ([]JobGroup, error) {
+		return instance.GetJobGroups()
+	})
+}
+
+// GetWorkers fetches the workers of all instances
+func (f *Federation) GetWorkers() ([]Worker, error) {
+	return federate(f, func(instance *Instance) ([]Worker, error) {
+		return instance.GetWorkers()
+	})
+}
@@ -0,0 +1,133 @@
+package gopenqa
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// EventPredicate decides if an event is delivered by a subscription
+type EventPredicate func(event Event) bool
+
+/* EventFilter defines which events are delivered by a subscription. Empty fields match all events.
+ * If a field is set but the event does not carry the corresponding attribute (e.g. a build for a comment event), the event does not match
+ */
+type EventFilter struct {
+	Keys     []string // Routing key patterns. "*" matches exactly one word, "#" zero or more words
+	GroupIDs []int    // Job group IDs
+	Build    string   // Glob pattern for the build
+	Test     string   // Regular expression for the test name
+	Arch     []string // Accepted architectures
+	Flavor   string   // Glob pattern for the flavor
+	Results  []string // Accepted job results
+}
+
+// MatchRoutingKey matches the given routing key against a AMQP topic pattern. "*" matches exactly one word, "#" zero or more words
+func MatchRoutingKey(pattern string, key string) bool {
+	return matchWords(strings.Split(pattern, "."), strings.Split(key, "."))
+}
+
+func matchWords(pattern []string, words []string) bool {
+	if len(pattern) == 0 {
+		return len(words) == 0
+	}
+	if pattern[0] == "#" {
+		// "#" matches zero or more words
+		for i := 0; i <= len(words); i++ {
+			if matchWords(pattern[1:], words[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+	if len(words) == 0 {
+		return false
+	}
+	if pattern[0] != "*" && pattern[0] != words[0] {
+		return false
+	}
+	return matchWords(pattern[1:], words[1:])
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+/* Predicate compiles the filter into a EventPredicate. Returns an error if a pattern is invalid */
+func (f EventFilter) Predicate() (EventPredicate, error) {
+	var test *regexp.Regexp
+	if f.Test != "" {
+		var err error
+		if test, err = regexp.Compile(f.Test); err != nil {
+			return nil, err
+		}
+	}
+	// Check glob patterns once, so that matching cannot fail later
+	for _, pattern := range []string{f.Build, f.Flavor} {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid pattern: %s", pattern)
+		}
+	}
+
+	return func(event Event) bool {
+		if len(f.Keys) > 0 {
+			found := false
+			for _, pattern := range f.Keys {
+				if MatchRoutingKey(pattern, event.Key) {
+					found = true
+					break
+				}
+			}
+			if !found {
+				return false
+			}
+		}
+		if len(f.GroupIDs) > 0 {
+			found := false
+			for _, id := range f.GroupIDs {
+				if id == event.GroupID() {
+					found = true
+					break
+				}
+			}
+			if !found {
+				return false
+			}
+		}
+		if f.Build != "" {
+			if matched, _ := path.Match(f.Build, event.Build()); !matched {
+				return false
+			}
+		}
+		if f.Flavor != "" {
+			if matched, _ := path.Match(f.Flavor, event.Flavor()); !matched {
+				return false
+			}
+		}
+		if test != nil && !test.MatchString(event.Test()) {
+			return false
+		}
+		if len(f.Arch) > 0 && !containsString(f.Arch, event.Arch()) {
+			return false
+		}
+		if len(f.Results) > 0 && !containsString(f.Results, event.Result()) {
+			return false
+		}
+		return true
+	}, nil
+}
+
+/* Matches returns true, if the given event matches the filter. Invalid patterns never match */
+func (f EventFilter) Matches(event Event) bool {
+	predicate, err := f.Predicate()
+	if err != nil {
+		return false
+	}
+	return predicate(event)
+}
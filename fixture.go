@@ -0,0 +1,178 @@
+package gopenqa
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// Request headers, which contain credentials and are therefore never written to a fixture
+var redactedHeaders = []string{"X-API-Key", "X-API-Hash", "X-API-Microtime", "Authorization", "Cookie"}
+
+// Response headers, which are not stored in a fixture
+var droppedHeaders = []string{"Set-Cookie", "Date"}
+
+const REDACTED = "REDACTED"
+
+/* Fixture is a recorded request/response pair */
+type Fixture struct {
+	Method        string      `json:"method"`
+	Path          string      `json:"path"`                     // Request path including the query
+	RequestHeader http.Header `json:"request_header,omitempty"` // Request headers, credentials are redacted
+	RequestBody   string      `json:"request_body,omitempty"`
+	Status        int         `json:"status"`
+	Header        http.Header `json:"header,omitempty"` // Response headers
+	Body          string      `json:"body"`             // Response body
+}
+
+/* Recorder is a http.RoundTripper, which performs the requests via the given transport and stores each request/response pair as fixture in a directory */
+type Recorder struct {
+	Dir       string
+	transport http.RoundTripper
+	mutex     *sync.Mutex
+}
+
+/* Replayer is a http.RoundTripper, which serves the responses from the fixtures in a directory instead of performing the requests */
+type Replayer struct {
+	Dir string
+}
+
+// fixtureFile returns the filename of the fixture for the given request, relative to the fixture directory
+// The request path becomes the directory (e.g. "api/v1/jobs/5830/comments/GET.json"), queries are distinguished by their hash
+// Parameters are sorted before hashing, as queries are often built from maps
+func fixtureFile(method string, urlPath string, query string) string {
+	dir := strings.TrimPrefix(path.Clean("/"+urlPath), "/")
+	name := strings.ToUpper(method)
+	if values, err := url.ParseQuery(query); err == nil {
+		query = values.Encode()
+	}
+	if query != "" {
+		name += fmt.Sprintf("_%x", sha1.Sum([]byte(query)))[:13]
+	}
+	return filepath.Join(filepath.FromSlash(dir), name+".json")
+}
+
+func redactHeader(header http.Header) http.Header {
+	ret := header.Clone()
+	for _, name := range redactedHeaders {
+		if ret.Get(name) != "" {
+			ret.Set(name, REDACTED)
+		}
+	}
+	return ret
+}
+
+/* NewRecorder creates a new recorder, which stores the fixtures in the given directory. If transport is nil, http.DefaultTransport is used */
+func NewRecorder(dir string, transport http.RoundTripper) *Recorder {
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	return &Recorder{Dir: dir, transport: transport, mutex: &sync.Mutex{}}
+}
+
+/* RoundTrip performs the request and records it. Previous recordings of the same request are overwritten */
+func (rec *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	fixture := Fixture{Method: req.Method, Path: req.URL.RequestURI(), RequestHeader: redactHeader(req.Header)}
+	if req.Body != nil {
+		buf, err := io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		fixture.RequestBody = string(buf)
+		req.Body = io.NopCloser(bytes.NewReader(buf))
+	}
+
+	resp, err := rec.transport.RoundTrip(req)
+	if err != nil {
+		return resp, err
+	}
+	buf, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(buf))
+	fixture.Status = resp.StatusCode
+	fixture.Header = resp.Header.Clone()
+	for _, name := range droppedHeaders {
+		fixture.Header.Del(name)
+	}
+	fixture.Body = string(buf)
+
+	if err := rec.write(fixtureFile(req.Method, req.URL.Path, req.URL.RawQuery), fixture); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+func (rec *Recorder) write(filename string, fixture Fixture) error {
+	rec.mutex.Lock()
+	defer rec.mutex.Unlock()
+	filename = filepath.Join(rec.Dir, filename)
+	if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+		return err
+	}
+	buf, err := json.MarshalIndent(fixture, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filename, append(buf, '\n'), 0644)
+}
+
+/* NewReplayer creates a new replayer, which serves the fixtures from the given directory */
+func NewReplayer(dir string) *Replayer {
+	return &Replayer{Dir: dir}
+}
+
+/* Fixture returns the recorded fixture for the given request */
+func (rep *Replayer) Fixture(method string, urlPath string, query string) (Fixture, error) {
+	var fixture Fixture
+	buf, err := os.ReadFile(filepath.Join(rep.Dir, fixtureFile(method, urlPath, query)))
+	if err != nil {
+		if os.IsNotExist(err) {
+			if query != "" {
+				urlPath += "?" + query
+			}
+			return fixture, fmt.Errorf("no fixture for %s %s", method, urlPath)
+		}
+		return fixture, err
+	}
+	err = json.Unmarshal(buf, &fixture)
+	return fixture, err
+}
+
+/* RoundTrip serves the response of the given request from the recorded fixture. No network connection is made */
+func (rep *Replayer) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Body != nil {
+		req.Body.Close()
+	}
+	fixture, err := rep.Fixture(req.Method, req.URL.Path, req.URL.RawQuery)
+	if err != nil {
+		return nil, err
+	}
+	header := fixture.Header
+	if header == nil {
+		header = make(http.Header, 0)
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", fixture.Status, http.StatusText(fixture.Status)),
+		StatusCode:    fixture.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(strings.NewReader(fixture.Body)),
+		ContentLength: int64(len(fixture.Body)),
+		Request:       req,
+	}, nil
+}
@@ -21,10 +21,13 @@ type Instance struct {
 	apikey        string
 	apisecret     string
 	verbose       bool
-	maxRecursions int        // Maximum number of recursions
-	userAgent     string     // Useragent sent with the request
-	allowParallel bool       // Allow parallel requests (default: No)
-	mutFetching   sync.Mutex // Mutex to ensure only one request at the time is performed
+	maxRecursions int               // Maximum number of recursions
+	userAgent     string            // Useragent sent with the request
+	allowParallel bool              // Allow parallel requests (default: No)
+	mutFetching   sync.Mutex        // Mutex to ensure only one request at the time is performed
+	transport     http.RoundTripper // Transport for the HTTP requests, if not the default one
+	concurrency   int               // Maximum number of concurrent requests for resolving cloned jobs
+	cache         *Cache            // Cache for GET requests, if enabled
 }
 
 // the settings are given as dict:
@@ -48,6 +51,14 @@ type productSettings struct {
 	Settings []map[string]string `json:"settings"`
 }
 
+// same as machineSettings for TestSuite
+type testSuiteSettings struct {
+	ID          int                 `json:"id"`
+	Name        string              `json:"name"`
+	Description string              `json:"description"`
+	Settings    []map[string]string `json:"settings"`
+}
+
 func convertSettingsFrom(settings map[string]string) []map[string]string {
 	ret := make([]map[string]string, 0)
 	for k, v := range settings {
@@ -101,6 +112,21 @@ func (w *productSettings) toProduct() Product {
 	return p
 }
 
+func (ts *testSuiteSettings) toTestSuite() TestSuite {
+	return TestSuite{ID: ts.ID, Name: ts.Name, Description: ts.Description, Settings: convertSettingsTo(ts.Settings)}
+}
+
+/* Get www-form-urlencoded parameters of the given TestSuite */
+func encodeTestSuite(ts TestSuite) string {
+	params := url.Values{}
+	params.Add("name", ts.Name)
+	params.Add("description", ts.Description)
+	for k, v := range ts.Settings {
+		params.Add("settings["+k+"]", v)
+	}
+	return params.Encode()
+}
+
 func createProduct2(p Product) productSettings {
 	w := productSettings{}
 	w.Arch = p.Arch
@@ -149,7 +175,7 @@ func EmptyParams() map[string]string {
 
 /* Create a openQA instance module */
 func CreateInstance(url string) Instance {
-	return Instance{URL: url, maxRecursions: 10, verbose: false, userAgent: "gopenqa", allowParallel: false}
+	return Instance{URL: url, maxRecursions: 10, verbose: false, userAgent: "gopenqa", allowParallel: false, concurrency: defaultConcurrency}
 }
 
 /* Create a openQA instance module for openqa.opensuse.org */
@@ -183,6 +209,26 @@ func (i *Instance) SetAllowParallel(allow bool) {
 	i.allowParallel = allow
 }
 
+// Set the maximum number of concurrent requests for resolving cloned jobs. Requests are still serialized, unless parallel requests are allowed
+func (i *Instance) SetConcurrency(concurrency int) {
+	i.concurrency = concurrency
+}
+
+// Set the transport for HTTP requests. nil resets to the default transport
+func (i *Instance) SetTransport(transport http.RoundTripper) {
+	i.transport = transport
+}
+
+// Record all requests and responses as fixtures into the given directory. API keys and hashes are redacted
+func (i *Instance) Record(dir string) {
+	i.transport = NewRecorder(dir, i.transport)
+}
+
+// Serve all requests from the fixtures in the given directory instead of contacting the instance
+func (i *Instance) Replay(dir string) {
+	i.transport = NewReplayer(dir)
+}
+
 func assignInstance(jobs []Job, instance *Instance) []Job {
 	for i, j := range jobs {
 		j.instance = instance
@@ -216,6 +262,16 @@ func (i *Instance) get(url string, data []byte) ([]byte, error) {
 	return i.request("GET", url, data)
 }
 
+/* Perform a GET request on the given url like get, but revalidate cached finished jobs
+ * Finished jobs may have been restarted in the meantime, which matters when following clones
+ */
+func (i *Instance) getLatest(url string) ([]byte, error) {
+	if i.cache == nil {
+		return i.get(url, nil)
+	}
+	return i.cachedGet(url, true)
+}
+
 /* Perform a POST request on the given url, and send the data as JSON if given
  * Add the APIKEY and APISECRET credentials, if given
  */
@@ -223,6 +279,13 @@ func (i *Instance) post(url string, data []byte) ([]byte, error) {
 	return i.request("POST", url, data)
 }
 
+/* Perform a PUT request on the given url, and send the data as JSON if given
+ * Add the APIKEY and APISECRET credentials, if given
+ */
+func (i *Instance) put(url string, data []byte) ([]byte, error) {
+	return i.request("PUT", url, data)
+}
+
 /* Perform a DELETE request on the given url, and send the data as JSON if given
  * Add the APIKEY and APISECRET credentials, if given
  */
@@ -234,16 +297,14 @@ func (i *Instance) delete(url string, data []byte) ([]byte, error) {
  * Add the APIKEY and APISECRET credentials, if given
  */
 func (i *Instance) request(method string, url string, data []byte) ([]byte, error) {
-	// Request mutex to ensure, only one request at the time
-	if !i.allowParallel {
-		i.mutFetching.Lock()
-		defer i.mutFetching.Unlock()
+	if i.cache != nil {
+		if method == "GET" && len(data) == 0 {
+			return i.cachedGet(url, false)
+		}
+		defer i.cache.invalidateWrite(url)
 	}
-
 	contentType := ""
-	if data == nil {
-		data = make([]byte, 0)
-	} else if len(data) > 0 {
+	if len(data) > 0 {
 		/* Don't do json, but pass it as url encoded form data!
 		var err error
 		if buf, err = json.Marshal(data); err != nil {
@@ -253,12 +314,61 @@ func (i *Instance) request(method string, url string, data []byte) ([]byte, erro
 		// TODO: Marshall data to URL encoded form data
 		contentType = "application/x-www-form-urlencoded"
 	}
+	status, buf, err := i.send(method, url, contentType, data)
+	if err != nil {
+		return buf, err
+	}
+	return i.checkStatus(status, buf)
+}
+
+// checkStatus returns an error if the status code of a response is not 200
+func (i *Instance) checkStatus(status int, buf []byte) ([]byte, error) {
+	if status != 200 {
+		if i.verbose {
+			fmt.Fprintf(os.Stderr, "%s\n", string(buf))
+		}
+		return buf, fmt.Errorf("http status code %d", status)
+	}
+	return buf, nil
+}
 
+/* Do performs a request on the given path of the instance (e.g. "/api/v1/jobs?state=running") with the credentials of the instance
+ * The body is sent with the given content type, if not empty. Returns the HTTP status code and the response body
+ * Unlike the other methods, a status code other than 200 is not considered an error. Responses are never cached
+ */
+func (i *Instance) Do(method string, path string, contentType string, body []byte) (int, []byte, error) {
+	url := fmt.Sprintf("%s/%s", strings.TrimSuffix(i.URL, "/"), strings.TrimPrefix(path, "/"))
+	if i.cache != nil && method != "GET" {
+		defer i.cache.invalidateWrite(url)
+	}
+	return i.send(method, url, contentType, body)
+}
+
+// send performs a signed request and returns the status code and the response body
+func (i *Instance) send(method string, url string, contentType string, data []byte) (int, []byte, error) {
+	status, _, buf, err := i.sendHeader(method, url, contentType, nil, data)
+	return status, buf, err
+}
+
+// sendHeader performs a signed request with the given additional headers and returns the status code, the response headers and the response body
+func (i *Instance) sendHeader(method string, url string, contentType string, header map[string]string, data []byte) (int, http.Header, []byte, error) {
+	// Request mutex to ensure, only one request at the time
+	if !i.allowParallel {
+		i.mutFetching.Lock()
+		defer i.mutFetching.Unlock()
+	}
+
+	if data == nil {
+		data = make([]byte, 0)
+	}
 	req, err := http.NewRequest(method, url, bytes.NewBuffer(data))
 	if err != nil {
-		return make([]byte, 0), err
+		return 0, nil, make([]byte, 0), err
 	}
 	req.Header.Add("Content-Type", contentType)
+	for name, value := range header {
+		req.Header.Set(name, value)
+	}
 	if i.userAgent != "" {
 		req.Header.Set("User-Agent", i.userAgent)
 	}
@@ -280,27 +390,16 @@ func (i *Instance) request(method string, url string, data []byte) ([]byte, erro
 
 	}
 	// Perform request on a new http client
-	c := http.Client{}
+	c := http.Client{Transport: i.transport}
 	r, err := c.Do(req)
 	if err != nil {
-		return make([]byte, 0), err
+		return 0, nil, make([]byte, 0), err
 	}
 
 	// First read body to have it ready in case of errors
 	defer r.Body.Close()
 	buf, err := io.ReadAll(r.Body) // TODO: Limit read size
-	if err != nil {
-		return buf, err
-	}
-
-	// Check status code
-	if r.StatusCode != 200 {
-		if i.verbose {
-			fmt.Fprintf(os.Stderr, "%s\n", string(buf))
-		}
-		return buf, fmt.Errorf("http status code %d", r.StatusCode)
-	}
-	return buf, nil
+	return r.StatusCode, r.Header, buf, err
 }
 
 /* Query the job overview. params is a map for optional parameters, which will be added to the query.
@@ -388,50 +487,162 @@ func (job *Job) applyInstance(i *Instance) {
 // GetJob fetches detailled job information
 func (i *Instance) GetJob(id int64) (Job, error) {
 	url := fmt.Sprintf("%s/api/v1/jobs/%d", i.URL, id)
-	job, err := i.fetchJob(url)
+	job, err := i.fetchJob(url, false)
 	return job, err
 }
 
-// GetJob fetches detailled information about a list of jobs
-func (i *Instance) GetJobs(ids []int64) ([]Job, error) {
-	if len(ids) == 0 {
-		return make([]Job, 0), nil
+// GetJobsByBuild fetches all jobs of the given build. params are added to the query, e.g. `groupid`, `distri` or `version`
+func (i *Instance) GetJobsByBuild(build string, params map[string]string) ([]Job, error) {
+	query := map[string]string{"build": build}
+	for k, v := range params {
+		query[k] = v
 	}
+	url := fmt.Sprintf("%s/api/v1/jobs?%s", i.URL, mergeParams(query))
+	return i.fetchJobsArray(url)
+}
+
+// SearchJobs fetches all jobs matching the given query. With query.FollowClones cloned jobs are replaced by their most recent clone
+func (i *Instance) SearchJobs(query JobQuery) ([]Job, error) {
 	url := fmt.Sprintf("%s/api/v1/jobs", i.URL)
-	// Add job ids to URL
-	// Note: I'm not using strings.Join because that requires me to first convert ids to a []string and I believe the following approach is not worse
-	first := true
-	for _, id := range ids {
-		if first {
-			first = false
-			url = fmt.Sprintf("%s?ids=%d", url, id)
-		} else {
-			url = fmt.Sprintf("%s&ids=%d", url, id)
+	if params := query.encode(); params != "" {
+		url += "?" + params
+	}
+	jobs, err := i.fetchJobsArray(url)
+	if err != nil || !query.FollowClones {
+		return jobs, err
+	}
+	responses := make([]JobResponse, 0)
+	for _, job := range jobs {
+		responses = append(responses, JobResponse{ID: job.ID, Job: job})
+	}
+	i.followClones(responses)
+	// A clone may also be part of the result. Return each job only once
+	ret := make([]Job, 0)
+	seen := make(map[int64]bool, 0)
+	for _, response := range responses {
+		if response.Err != nil {
+			return ret, response.Err
+		}
+		if !seen[response.Job.ID] {
+			seen[response.Job.ID] = true
+			ret = append(ret, response.Job)
 		}
 	}
-	return i.fetchJobsArray(url)
+	return ret, nil
+}
+
+// Maximum length of the URL for fetching multiple jobs at once. Longer ID lists are split into multiple requests
+const maxJobsURLLength = 4096
+
+// Default number of concurrent requests for resolving cloned jobs
+const defaultConcurrency = 4
+
+/* Result of fetching a single job of a list of jobs */
+type JobResponse struct {
+	ID  int64 // Requested job ID
+	Job Job   // Fetched job. If clones are followed, this is the most recent clone
+	Err error // Error fetching the job, e.g. if the job does not exist
+
+	notFound bool
+}
+
+// GetJob fetches detailled information about a list of jobs. Jobs that do not exist are omitted
+func (i *Instance) GetJobs(ids []int64) ([]Job, error) {
+	return jobsOf(i.GetJobResponses(ids, false))
 }
 
 // GetJob fetches detailled information about a list of jobs. Follows cloned jobs, if applicable
 func (inst *Instance) GetJobsFollow(ids []int64) ([]Job, error) {
-	jobs, err := inst.GetJobs(ids)
-	if err != nil {
-		return jobs, err
+	return jobsOf(inst.GetJobResponses(ids, true))
+}
+
+// jobsOf returns the fetched jobs of the given responses and the first error other than a missing job
+func jobsOf(responses []JobResponse) ([]Job, error) {
+	jobs := make([]Job, 0)
+	for _, response := range responses {
+		if response.notFound {
+			continue
+		} else if response.Err != nil {
+			return jobs, response.Err
+		}
+		jobs = append(jobs, response.Job)
 	}
+	return jobs, nil
+}
 
-	// Fetch cloned jobs one by one. Since it is possible for a job to have two cloned jobs
-	// the relation between an original job and it's cloned job is not directly visible.
-	// This means we have to fetch each job individually, so that we can keep track of the jobs origin
-	for i, job := range jobs {
-		if job.IsCloned() {
-			job, err := inst.GetJobFollow(job.ID)
+/* GetJobResponses fetches the given jobs and returns a response per ID in the order of the given IDs
+ * IDs are fetched in batches that keep the URL short enough. With followClones, cloned jobs are replaced by their most recent clone.
+ * Clones are resolved concurrently, see SetConcurrency. Errors are reported per ID
+ */
+func (i *Instance) GetJobResponses(ids []int64, followClones bool) []JobResponse {
+	responses := make([]JobResponse, len(ids))
+	for n, id := range ids {
+		responses[n].ID = id
+	}
+	base := fmt.Sprintf("%s/api/v1/jobs?", i.URL)
+	for start := 0; start < len(ids); {
+		// Add job ids to URL until the URL would get too long
+		end := start
+		url := base
+		for end < len(ids) {
+			param := fmt.Sprintf("ids=%d", ids[end])
+			if end > start {
+				if len(url)+len(param)+1 > maxJobsURLLength {
+					break
+				}
+				param = "&" + param
+			}
+			url += param
+			end++
+		}
+		jobs, err := i.fetchJobsArray(url)
+		found := make(map[int64]Job, 0)
+		for _, job := range jobs {
+			found[job.ID] = job
+		}
+		for n := start; n < end; n++ {
 			if err != nil {
-				return jobs, err
+				responses[n].Err = err
+			} else if job, ok := found[ids[n]]; ok {
+				responses[n].Job = job
+			} else {
+				responses[n].Err = fmt.Errorf("job %d not found", ids[n])
+				responses[n].notFound = true
 			}
-			jobs[i] = job
 		}
+		start = end
 	}
-	return jobs, nil
+	if followClones {
+		i.followClones(responses)
+	}
+	return responses
+}
+
+// followClones replaces cloned jobs of the given responses by their most recent clone, using a bounded number of concurrent requests
+func (i *Instance) followClones(responses []JobResponse) {
+	workers := i.concurrency
+	if workers < 1 {
+		workers = 1
+	}
+	queue := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for n := range queue {
+				// Each response is only modified by a single worker
+				responses[n].Job, responses[n].Err = i.GetJobFollow(responses[n].Job.CloneID)
+			}
+		}()
+	}
+	for n, response := range responses {
+		if response.Err == nil && response.Job.IsCloned() {
+			queue <- n
+		}
+	}
+	close(queue)
+	wg.Wait()
 }
 
 func (i *Instance) DeleteJob(id int64) error {
@@ -447,7 +658,7 @@ func (i *Instance) DeleteJob(id int64) error {
 func (inst *Instance) GetJobFollow(id int64) (Job, error) {
 	for recursion := 0; recursion < inst.maxRecursions; recursion++ {
 		url := fmt.Sprintf("%s/api/v1/jobs/%d", inst.URL, id)
-		job, err := inst.fetchJob(url)
+		job, err := inst.fetchJob(url, true)
 		if err != nil {
 			return job, err
 		}
@@ -496,6 +707,26 @@ func (i *Instance) PostJobGroup(jobgroup JobGroup) (JobGroup, error) {
 	return jobgroup, err
 }
 
+// GetBuildResults fetches the build results overview of a job group, as shown on the group page. limit defines the maximum number of builds (0 for the openQA default)
+func (i *Instance) GetBuildResults(groupID int, limit int) (BuildResults, error) {
+	var results BuildResults
+	url := fmt.Sprintf("%s/group_overview/%d.json", i.URL, groupID)
+	if limit > 0 {
+		url += fmt.Sprintf("?limit_builds=%d", limit)
+	}
+	buf, err := i.get(url, nil)
+	if err != nil {
+		return results, err
+	}
+	if err := json.Unmarshal(buf, &results); err != nil {
+		return results, err
+	}
+	if results.Builds == nil {
+		results.Builds = make([]BuildResult, 0)
+	}
+	return results, nil
+}
+
 func (i *Instance) GetParentJobGroups() ([]JobGroup, error) {
 	url := fmt.Sprintf("%s/api/v1/parent_groups", i.URL)
 	return i.fetchJobGroups(url)
@@ -526,11 +757,74 @@ func (i *Instance) PostParentJobGroup(jobgroup JobGroup) (JobGroup, error) {
 	return jobgroup, err
 }
 
+// UpdateJobGroup updates the existing job group with the ID of the given job group
+func (i *Instance) UpdateJobGroup(jobgroup JobGroup) (JobGroup, error) {
+	rurl := fmt.Sprintf("%s/api/v1/job_groups/%d", i.URL, jobgroup.ID)
+	_, err := i.put(rurl, []byte(jobgroup.encodeWWW()))
+	return jobgroup, err
+}
+
+// UpdateParentJobGroup updates the existing parent job group with the ID of the given job group
+func (i *Instance) UpdateParentJobGroup(jobgroup JobGroup) (JobGroup, error) {
+	rurl := fmt.Sprintf("%s/api/v1/parent_groups/%d", i.URL, jobgroup.ID)
+	_, err := i.put(rurl, []byte(jobgroup.encodeWWW()))
+	return jobgroup, err
+}
+
 func (i *Instance) GetWorkers() ([]Worker, error) {
 	url := fmt.Sprintf("%s/api/v1/workers", i.URL)
 	return i.fetchWorkers(url)
 }
 
+// GetWorker fetches detailled worker information
+func (i *Instance) GetWorker(id int) (Worker, error) {
+	url := fmt.Sprintf("%s/api/v1/workers/%d", i.URL, id)
+	return i.fetchWorker(url)
+}
+
+// DeleteWorker deletes the given worker. openQA only allows to delete offline workers
+func (i *Instance) DeleteWorker(id int) error {
+	url := fmt.Sprintf("%s/api/v1/workers/%d", i.URL, id)
+	buf, err := i.delete(url, nil)
+	if i.verbose {
+		fmt.Fprintf(os.Stderr, "%s\n", string(buf))
+	}
+	return err
+}
+
+// SendWorkerCommand sends a command (e.g. WorkerCommandQuit) to the given worker
+func (i *Instance) SendWorkerCommand(id int, command string) error {
+	if !IsWorkerCommand(command) {
+		return fmt.Errorf("invalid worker command: %s", command)
+	}
+	params := url.Values{}
+	params.Add("command", command)
+	rurl := fmt.Sprintf("%s/api/v1/workers/%d/commands", i.URL, id)
+	buf, err := i.post(rurl, []byte(params.Encode()))
+	if i.verbose {
+		fmt.Fprintf(os.Stderr, "%s\n", string(buf))
+	}
+	return err
+}
+
+// QueryWorkers fetches all workers and returns the ones matching the given query
+func (i *Instance) QueryWorkers(query WorkerQuery) ([]Worker, error) {
+	workers, err := i.GetWorkers()
+	if err != nil {
+		return workers, err
+	}
+	return FilterWorkers(workers, query), nil
+}
+
+// GetWorkerCapacity fetches all workers and reports the idle/busy/offline slots per worker class
+func (i *Instance) GetWorkerCapacity() ([]WorkerCapacity, error) {
+	workers, err := i.GetWorkers()
+	if err != nil {
+		return make([]WorkerCapacity, 0), err
+	}
+	return WorkerCapacityReport(workers), nil
+}
+
 // fetchJobs fetches the given url and returns all jobs returned by it (as direct array)
 func (inst *Instance) fetchJobs(url string) ([]Job, error) {
 	jobs := make([]Job, 0)
@@ -574,6 +868,9 @@ func (inst *Instance) fetchJobGroups(url string) ([]JobGroup, error) {
 	}
 	// TODO: Sometimes SizeLimit is returned as string but it should be an int. Fix this.
 	err = json.Unmarshal(resp, &jobs)
+	for j := range jobs {
+		jobs[j].Remote = inst.URL
+	}
 	return jobs, err
 }
 
@@ -586,11 +883,34 @@ func (i *Instance) fetchWorkers(url string) ([]Worker, error) {
 	workers := make(map[string][]Worker, 0)
 	err = json.Unmarshal(resp, &workers)
 	if workers, ok := workers["workers"]; ok {
+		for j, worker := range workers {
+			worker.applyInstance(i)
+			workers[j] = worker
+		}
 		return workers, err
 	}
 	return make([]Worker, 0), nil
 }
 
+func (i *Instance) fetchWorker(url string) (Worker, error) {
+	type ResultWorker struct { // Expected result structure
+		Worker Worker `json:"worker"`
+	}
+	var worker ResultWorker
+	resp, err := i.get(url, nil)
+	if err != nil {
+		return worker.Worker, err
+	}
+	if err := json.Unmarshal(resp, &worker); err != nil {
+		return worker.Worker, err
+	}
+	if worker.Worker.ID == 0 {
+		return worker.Worker, fmt.Errorf("not found")
+	}
+	worker.Worker.applyInstance(i)
+	return worker.Worker, nil
+}
+
 func (i *Instance) fetchJobTemplates(url string) ([]JobTemplate, error) {
 	resp, err := i.get(url, nil)
 	if err != nil {
@@ -626,12 +946,36 @@ func (i *Instance) fetchMachines(url string) ([]Machine, error) {
 	return make([]Machine, 0), nil
 }
 
-func (inst *Instance) fetchJob(url string) (Job, error) {
+func (i *Instance) fetchTestSuites(url string) ([]TestSuite, error) {
+	resp, err := i.get(url, nil)
+	if err != nil {
+		return make([]TestSuite, 0), err
+	}
+	// test suites come as a "TestSuites:[...]" dict
+	suites := make(map[string][]testSuiteSettings, 0)
+	if err := json.Unmarshal(resp, &suites); err != nil {
+		return make([]TestSuite, 0), err
+	}
+	ret := make([]TestSuite, 0)
+	for _, suite := range suites["TestSuites"] {
+		ret = append(ret, suite.toTestSuite())
+	}
+	return ret, nil
+}
+
+// fetchJob fetches a single job. With latest, a cached finished job is revalidated
+func (inst *Instance) fetchJob(url string, latest bool) (Job, error) {
 	type ResultJob struct { // Expected result structure
 		Job Job `json:"job"`
 	}
 	var job ResultJob
-	resp, err := inst.get(url, nil)
+	var resp []byte
+	var err error
+	if latest {
+		resp, err = inst.getLatest(url)
+	} else {
+		resp, err = inst.get(url, nil)
+	}
 	if err != nil {
 		return job.Job, err
 	}
@@ -671,6 +1015,9 @@ func mergeParams(params map[string]string) string {
  * if follow is set to true, the method will return the cloned job instead of the original one, if present
  */
 func (j *Job) FetchChildren(ids []int64, follow bool) ([]Job, error) {
+	if j.instance == nil {
+		return make([]Job, 0), fmt.Errorf("no instance assigned")
+	}
 	children, err := j.instance.GetJobs(ids)
 	if err != nil {
 		return children, err
@@ -727,17 +1074,13 @@ func (instance *Instance) GetJobGroupJobs(id int) ([]int64, error) {
 	}
 }
 
+/* DeleteJobGroupJobs deletes all jobs of the given job group and stops at the first failure */
 func (i *Instance) DeleteJobGroupJobs(id int) error {
-	if jobs, err := i.GetJobGroupJobs(id); err != nil {
+	report, err := i.DeleteJobGroupJobsWithOptions(id, DeleteOptions{})
+	if err != nil {
 		return err
-	} else {
-		for _, id := range jobs {
-			if err := i.DeleteJob(id); err != nil {
-				return err
-			}
-		}
 	}
-	return nil
+	return report.Err()
 }
 
 func (i *Instance) DeleteJobGroup(id int) error {
@@ -799,6 +1142,7 @@ func (i *Instance) GetMachine(id int) (Machine, error) {
 	}
 }
 
+// PostMachine creates the given machine, or updates it if the ID is set
 func (i *Instance) PostMachine(machine Machine) (Machine, error) {
 	if i.apikey == "" || i.apisecret == "" {
 		return Machine{}, fmt.Errorf("API key or secret not set")
@@ -830,7 +1174,12 @@ func (i *Instance) PostMachine(machine Machine) (Machine, error) {
 	if err != nil {
 		return Machine{}, err
 	}
-	if buf, err := i.post(rurl, buf); err != nil {
+	// openQA only accepts updates via PUT
+	send := i.post
+	if machine.ID != 0 {
+		send = i.put
+	}
+	if buf, err := send(rurl, buf); err != nil {
 		return Machine{}, err
 	} else {
 		err = json.Unmarshal(buf, &machine)
@@ -902,12 +1251,16 @@ func (i *Instance) GetProduct(id int) (Product, error) {
 	}
 }
 
+// PostProduct creates the given product, or updates it if the ID is set
 func (i *Instance) PostProduct(product Product) (Product, error) {
+	// openQA only accepts updates via PUT
 	rurl := ""
+	send := i.post
 	if product.ID == 0 {
 		rurl = fmt.Sprintf("%s/api/v1/products", i.URL)
 	} else {
 		rurl = fmt.Sprintf("%s/api/v1/products/%d", i.URL, product.ID)
+		send = i.put
 	}
 
 	// Product to values
@@ -916,7 +1269,7 @@ func (i *Instance) PostProduct(product Product) (Product, error) {
 	if i.verbose {
 		fmt.Fprintf(os.Stderr, "%s\n", data)
 	}
-	buf, err := i.post(rurl, data)
+	buf, err := send(rurl, data)
 	if i.verbose {
 		fmt.Fprintf(os.Stderr, "%s\n", string(buf))
 	}
@@ -927,6 +1280,52 @@ func (i *Instance) PostProduct(product Product) (Product, error) {
 	return product, err
 }
 
+func (i *Instance) GetTestSuites() ([]TestSuite, error) {
+	rurl := fmt.Sprintf("%s/api/v1/test_suites", i.URL)
+	return i.fetchTestSuites(rurl)
+}
+
+func (i *Instance) GetTestSuite(id int) (TestSuite, error) {
+	rurl := fmt.Sprintf("%s/api/v1/test_suites/%d", i.URL, id)
+	suites, err := i.fetchTestSuites(rurl)
+	if err != nil {
+		return TestSuite{}, err
+	}
+	if len(suites) == 0 {
+		return TestSuite{}, fmt.Errorf("not found")
+	}
+	return suites[0], nil
+}
+
+// PostTestSuite creates the given test suite, or updates it if the ID is set
+func (i *Instance) PostTestSuite(suite TestSuite) (TestSuite, error) {
+	// openQA only accepts updates via PUT
+	rurl := fmt.Sprintf("%s/api/v1/test_suites", i.URL)
+	send := i.post
+	if suite.ID != 0 {
+		rurl = fmt.Sprintf("%s/api/v1/test_suites/%d", i.URL, suite.ID)
+		send = i.put
+	}
+	buf, err := send(rurl, []byte(encodeTestSuite(suite)))
+	if i.verbose {
+		fmt.Fprintf(os.Stderr, "%s\n", string(buf))
+	}
+	if err != nil {
+		return TestSuite{}, err
+	}
+	err = json.Unmarshal(buf, &suite)
+	return suite, err
+}
+
+func (i *Instance) DeleteTestSuite(id int) error {
+	rurl := fmt.Sprintf("%s/api/v1/test_suites/%d", i.URL, id)
+	buf, err := i.delete(rurl, nil)
+	if i.verbose {
+		fmt.Fprintf(os.Stderr, "%s\n", string(buf))
+	}
+	return err
+}
+
 /* Fetch comments for a given job */
 func (i *Instance) GetComments(job int64) ([]Comment, error) {
 	ret := make([]Comment, 0)
@@ -941,3 +1340,55 @@ func (i *Instance) GetComments(job int64) ([]Comment, error) {
 	err = json.Unmarshal(buf, &ret)
 	return ret, err
 }
+
+// fetchComments fetches the comments from the given url
+func (i *Instance) fetchComments(url string) ([]Comment, error) {
+	ret := make([]Comment, 0)
+	buf, err := i.get(url, nil)
+	if i.verbose {
+		fmt.Fprintf(os.Stderr, "%s\n", string(buf))
+	}
+	if err != nil {
+		return ret, err
+	}
+	err = json.Unmarshal(buf, &ret)
+	return ret, err
+}
+
+// postComment posts a new comment with the given text to the given url and returns the ID of the new comment
+func (i *Instance) postComment(rurl string, text string) (int, error) {
+	params := url.Values{}
+	params.Add("text", text)
+	buf, err := i.post(rurl, []byte(params.Encode()))
+	if i.verbose {
+		fmt.Fprintf(os.Stderr, "%s\n", string(buf))
+	}
+	if err != nil {
+		return 0, err
+	}
+	var result struct {
+		ID int `json:"id"`
+	}
+	err = json.Unmarshal(buf, &result)
+	return result.ID, err
+}
+
+/* Fetch comments for a given job group */
+func (i *Instance) GetJobGroupComments(id int) ([]Comment, error) {
+	return i.fetchComments(fmt.Sprintf("%s/api/v1/groups/%d/comments", i.URL, id))
+}
+
+/* Fetch comments for a given parent job group */
+func (i *Instance) GetParentJobGroupComments(id int) ([]Comment, error) {
+	return i.fetchComments(fmt.Sprintf("%s/api/v1/parent_groups/%d/comments", i.URL, id))
+}
+
+/* Post a new comment to the given job group. Returns the ID of the new comment */
+func (i *Instance) PostJobGroupComment(id int, text string) (int, error) {
+	return i.postComment(fmt.Sprintf("%s/api/v1/groups/%d/comments", i.URL, id), text)
+}
+
+/* Post a new comment to the given parent job group. Returns the ID of the new comment */
+func (i *Instance) PostParentJobGroupComment(id int, text string) (int, error) {
+	return i.postComment(fmt.Sprintf("%s/api/v1/parent_groups/%d/comments", i.URL, id), text)
+}
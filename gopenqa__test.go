@@ -6,11 +6,19 @@ package gopenqa
  */
 
 import (
+	"encoding/json"
+	"fmt"
 	"log"
+	"net"
 	"net/http"
 	"os"
+	"path/filepath"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
+	amqp "github.com/rabbitmq/amqp091-go"
 	"gotest.tools/assert"
 )
 
@@ -22,8 +30,14 @@ const COMMENT_TEST_JOB_ID = 5830
 func setupTestServer() {
 	fs := http.FileServer(http.Dir("./test"))
 	http.Handle("/api/v1/", http.StripPrefix("/api/v1/", fs))
+	http.Handle("/group_overview/", fs)
+	// Listen before returning to ensure the server is ready when the tests start
+	listener, err := net.Listen("tcp", ":8421")
+	if err != nil {
+		panic(err)
+	}
 	go func() {
-		if err := http.ListenAndServe(":8421", nil); err != nil {
+		if err := http.Serve(listener, nil); err != nil {
 			panic(err)
 		}
 	}()
@@ -77,6 +91,74 @@ func TestWorkers(t *testing.T) {
 		log.Fatalf("Expected 2 workers, got %d", len(workers))
 		return
 	}
+	// Fields added by the program are encoded in lower case, like the fetched ones
+	buf, err := json.Marshal(workers[0])
+	assert.NilError(t, err)
+	assert.Assert(t, strings.Contains(string(buf), `"link":"`+instance.URL+`/admin/workers/`), string(buf))
+	assert.Assert(t, strings.Contains(string(buf), `"remote":"`+instance.URL+`"`), string(buf))
+	// Like the other federated types
+	buf, err = json.Marshal(JobGroup{ID: 1, Name: "openSUSE Tumbleweed", Remote: instance.URL})
+	assert.NilError(t, err)
+	assert.Assert(t, strings.Contains(string(buf), `"remote":"`+instance.URL+`"`), string(buf))
+	job := Job{ID: 1}
+	job.applyInstance(&instance)
+	buf, err = json.Marshal(job)
+	assert.NilError(t, err)
+	assert.Assert(t, strings.Contains(string(buf), `"link":"`+instance.URL+`/tests/1"`), string(buf))
+	assert.Assert(t, strings.Contains(string(buf), `"remote":"`+instance.URL+`"`), string(buf))
+}
+
+func TestWorkerCapacity(t *testing.T) {
+	workers, err := instance.QueryWorkers(WorkerQuery{Host: "d465", WorkerClass: []string{"qemu_i686"}})
+	if err != nil {
+		log.Fatalf("%s", err)
+		return
+	}
+	assert.Equal(t, len(workers), 1)
+	assert.Equal(t, workers[0].ID, 2)
+	assert.DeepEqual(t, workers[0].WorkerClass(), []string{"qemu_x86_64", "qemu_i686", "qemu_i586"})
+	assert.Equal(t, workers[0].Link, "http://localhost:8421/admin/workers/2")
+	workers, _ = instance.QueryWorkers(WorkerQuery{Status: []string{"idle", "running"}})
+	assert.Equal(t, len(workers), 0)
+
+	workers = []Worker{
+		{ID: 1, Status: "idle", Properties: map[string]string{"WORKER_CLASS": "qemu_x86_64,tap"}},
+		{ID: 2, Status: "running", JobID: 42, Properties: map[string]string{"WORKER_CLASS": "qemu_x86_64"}},
+		{ID: 3, Status: "dead", Properties: map[string]string{"WORKER_CLASS": "qemu_x86_64"}},
+		{ID: 4, Status: "broken", Properties: map[string]string{"WORKER_CLASS": "tap"}},
+	}
+	report := WorkerCapacityReport(workers)
+	assert.DeepEqual(t, report, []WorkerCapacity{
+		{WorkerClass: "qemu_x86_64", Idle: 1, Busy: 1, Offline: 1, Total: 3},
+		{WorkerClass: "tap", Idle: 1, Broken: 1, Total: 2},
+	})
+}
+
+func TestWorkerLastSeen(t *testing.T) {
+	seen := time.Now().Add(-2 * time.Hour).UTC()
+	tests := []struct {
+		name    string
+		worker  Worker
+		seen    bool
+		offline time.Duration
+		prune   bool
+	}{
+		{"trailing Z", Worker{Status: "dead", Tseen: seen.Format("2006-01-02T15:04:05Z")}, true, time.Hour, true},
+		{"without Z", Worker{Status: "dead", Tseen: seen.Format("2006-01-02T15:04:05")}, true, time.Hour, true},
+		{"seen recently", Worker{Status: "dead", Tseen: seen.Format("2006-01-02T15:04:05")}, true, 3 * time.Hour, false},
+		{"never seen", Worker{Status: "dead"}, false, time.Hour, false},
+		{"never seen without duration", Worker{Status: "dead"}, false, 0, true},
+		{"online", Worker{Status: "idle", Tseen: seen.Format("2006-01-02T15:04:05")}, true, time.Hour, false},
+		{"invalid timestamp", Worker{Status: "dead", Tseen: "yesterday"}, false, time.Hour, false},
+	}
+	for _, test := range tests {
+		lastSeen, err := test.worker.LastSeen()
+		assert.Equal(t, err == nil, test.seen, test.name)
+		if test.seen {
+			assert.Equal(t, lastSeen.Unix(), seen.Unix(), test.name)
+		}
+		assert.Equal(t, test.worker.OfflineLongerThan(test.offline), test.prune, test.name)
+	}
 }
 
 func TestComments(t *testing.T) {
@@ -159,3 +241,462 @@ func TestProduct(t *testing.T) {
 	assert.Equal(t, products[2].Settings["BOOT_HDD_IMAGE"], "1")
 	assert.Equal(t, products[2].Settings["HDD_1"], "openSUSE-1-aarch64-DVD.iso")
 }
+
+func TestBuildResults(t *testing.T) {
+	results, err := instance.GetBuildResults(7, 10)
+	if err != nil {
+		log.Fatalf("%s", err)
+		return
+	}
+	assert.Equal(t, results.Group.ID, 7)
+	assert.Equal(t, results.Group.Name, "Public Cloud Updates")
+	assert.Equal(t, results.MaxJobs, 16)
+	if len(results.Builds) != 2 {
+		log.Fatalf("Expected 2 builds, got %d", len(results.Builds))
+		return
+	}
+	build := results.Builds[0]
+	assert.Equal(t, build.Build, "20210420-1")
+	assert.Equal(t, build.Version, "15-SP2")
+	assert.Equal(t, build.Passed, 10)
+	assert.Equal(t, build.Failed, 2)
+	assert.Equal(t, build.Softfailed, 1)
+	assert.Equal(t, build.Unfinished, 3)
+	assert.Equal(t, build.Pending(), 3)
+	assert.Assert(t, !build.Finished())
+	assert.Assert(t, !build.Reviewed)
+	assert.Assert(t, !build.AllPassed)
+	assert.Assert(t, !build.IsTagged())
+	assert.DeepEqual(t, build.Distris, []string{"sle"})
+	build = results.Builds[1]
+	assert.Equal(t, build.Passed, 15)
+	assert.Assert(t, build.Finished())
+	assert.Assert(t, build.Reviewed)
+	assert.Assert(t, build.AllPassed)
+	assert.Assert(t, build.IsTagged())
+	assert.Equal(t, build.Tag.Type, "important")
+	assert.Equal(t, build.Tag.Description, "GM candidate")
+}
+
+func TestEvents(t *testing.T) {
+	event, err := parseEvent(amqp.Delivery{RoutingKey: "suse.openqa.job.done", Body: []byte(`{"ARCH":"x86_64","BUILD":"20210419-1","TEST":"boot","group_id":7,"id":"5985","result":"passed","remaining":0}`)})
+	assert.NilError(t, err)
+	assert.Equal(t, event.Type, EventJobDone)
+	assert.Equal(t, event.Category(), "job")
+	assert.Assert(t, event.Job != nil)
+	assert.Equal(t, event.Job.ID, int64(5985))
+	assert.Equal(t, event.Job.GroupID, 7)
+	assert.Equal(t, event.Job.Result, "passed")
+
+	event, err = parseEvent(amqp.Delivery{RoutingKey: "opensuse.openqa.comment.create", Body: []byte(`{"id":14,"job_id":5830,"text":"poo#42","user":"phoenix"}`)})
+	assert.NilError(t, err)
+	assert.Equal(t, event.Type, EventCommentCreate)
+	assert.Assert(t, event.Comment != nil)
+	assert.Equal(t, event.Comment.JobID, int64(5830))
+	assert.Equal(t, event.Comment.Text, "poo#42")
+
+	event, err = parseEvent(amqp.Delivery{RoutingKey: "suse.openqa.iso.create", Body: []byte(`{"ARCH":"x86_64","BUILD":"42","DISTRI":"sle","scheduled_product_id":3,"FOO":"bar"}`)})
+	assert.NilError(t, err)
+	assert.Assert(t, event.ISO != nil)
+	assert.Equal(t, event.ISO.ScheduledProductID, int64(3))
+	assert.Equal(t, event.ISO.Settings["FOO"], "bar")
+
+	event, err = parseEvent(amqp.Delivery{RoutingKey: "suse.openqa.parentgroup.update", Body: []byte(`{"id":2,"name":"SLE 15"}`)})
+	assert.NilError(t, err)
+	assert.Assert(t, event.JobGroup != nil)
+	assert.Assert(t, event.JobGroup.Parent)
+
+	event, err = parseEvent(amqp.Delivery{RoutingKey: "suse.openqa.jobgroup.delete", Body: []byte(`{"id":7,"name":"openSUSE Tumbleweed"}`)})
+	assert.NilError(t, err)
+	assert.Equal(t, event.Type, EventJobGroupDelete)
+	assert.Assert(t, event.JobGroup != nil)
+	assert.Assert(t, !event.JobGroup.Parent)
+	assert.Equal(t, event.GroupID(), 7)
+	event, err = parseEvent(amqp.Delivery{RoutingKey: "suse.openqa.parentgroup.delete", Body: []byte(`{"id":2}`)})
+	assert.NilError(t, err)
+	assert.Equal(t, event.Type, EventParentGroupDelete)
+	assert.Assert(t, event.JobGroup != nil)
+	assert.Assert(t, event.JobGroup.Parent)
+	assert.Equal(t, event.GroupID(), 0)
+
+	event, err = parseEvent(amqp.Delivery{RoutingKey: "suse.openqa.user.login", Body: []byte(`{"user":"phoenix"}`)})
+	assert.NilError(t, err)
+	assert.Equal(t, event.Type, "user.login")
+	assert.Assert(t, !event.Known())
+	assert.Equal(t, string(event.Raw), `{"user":"phoenix"}`)
+}
+
+func TestEventFilter(t *testing.T) {
+	done, err := parseEvent(amqp.Delivery{RoutingKey: "suse.openqa.job.done", Body: []byte(`{"ARCH":"x86_64","BUILD":"20210419-1","FLAVOR":"DVD","TEST":"publiccloud_ltp_cve","group_id":7,"id":5995,"result":"failed"}`)})
+	assert.NilError(t, err)
+	comment, err := parseEvent(amqp.Delivery{RoutingKey: "suse.openqa.comment.create", Body: []byte(`{"id":14,"job_id":5995,"text":"poo#42"}`)})
+	assert.NilError(t, err)
+
+	assert.Assert(t, EventFilter{}.Matches(done))
+	assert.Assert(t, EventFilter{Keys: []string{"*.openqa.job.#"}}.Matches(done))
+	assert.Assert(t, EventFilter{Keys: []string{"#.done"}}.Matches(done))
+	assert.Assert(t, !EventFilter{Keys: []string{"*.openqa.job.#"}}.Matches(comment))
+	assert.Assert(t, !EventFilter{Keys: []string{"suse.openqa.*"}}.Matches(done))
+	assert.Assert(t, EventFilter{GroupIDs: []int{1, 7}, Build: "20210419-*", Test: "^publiccloud_", Arch: []string{"x86_64"}, Flavor: "DVD", Results: []string{"failed", "incomplete"}}.Matches(done))
+	assert.Assert(t, !EventFilter{GroupIDs: []int{1}}.Matches(done))
+	assert.Assert(t, !EventFilter{Build: "2022*"}.Matches(done))
+	assert.Assert(t, !EventFilter{Test: "ltp$"}.Matches(done))
+	assert.Assert(t, !EventFilter{Results: []string{"passed"}}.Matches(done))
+	assert.Assert(t, !EventFilter{GroupIDs: []int{7}}.Matches(comment))
+	_, err = EventFilter{Test: "("}.Predicate()
+	assert.Assert(t, err != nil)
+}
+
+func TestMatchRoutingKey(t *testing.T) {
+	tests := []struct {
+		pattern string
+		key     string
+		match   bool
+	}{
+		{"suse.openqa.job.done", "suse.openqa.job.done", true},
+		{"suse.openqa.job.done", "suse.openqa.job.create", false},
+		{"suse.openqa.job.*", "suse.openqa.job.done", true},
+		{"suse.openqa.*", "suse.openqa.job.done", false},
+		{"*.openqa.#", "opensuse.openqa.comment.create", true},
+		{"#", "suse.openqa.job.done", true},
+		{"suse.openqa.#", "suse.openqa", true},
+		{"#.done", "suse.openqa.job.done", true},
+		{"#.done", "suse.openqa.job.restart", false},
+		{"suse.#.done", "suse.openqa.job.done", true},
+		{"suse.openqa.job", "suse.openqa.job.done", false},
+	}
+	for _, test := range tests {
+		assert.Equal(t, MatchRoutingKey(test.pattern, test.key), test.match, "%s %s", test.pattern, test.key)
+	}
+}
+
+func TestEventEnrichment(t *testing.T) {
+	event, err := parseEvent(amqp.Delivery{RoutingKey: "suse.openqa.job.done", Body: []byte(`{"id":5985,"result":"passed"}`)})
+	assert.NilError(t, err)
+	_, err = event.FetchJob()
+	assert.Assert(t, err != nil) // not bound to an instance
+	event.SetInstance(&instance)
+	job, err := event.FetchJob()
+	assert.NilError(t, err)
+	assert.Equal(t, job.ID, int64(5985))
+	assert.Equal(t, job.Link, "http://localhost:8421/tests/5985")
+	assert.Equal(t, event.Link(), job.Link)
+
+	event, err = parseEvent(amqp.Delivery{RoutingKey: "suse.openqa.comment.create", Body: []byte(`{"id":17,"job_id":5830,"text":"bsc#1337"}`)})
+	assert.NilError(t, err)
+	event.SetInstance(&instance)
+	comments, err := event.FetchComments()
+	assert.NilError(t, err)
+	assert.Equal(t, len(comments), 4)
+}
+
+func TestRecordReplay(t *testing.T) {
+	dir := t.TempDir()
+	recording := CreateInstance(instance.URL)
+	recording.SetApiKey("1234567890ABCDEF", "FEDCBA0987654321")
+	recording.Record(dir)
+	comments, err := recording.GetComments(COMMENT_TEST_JOB_ID)
+	assert.NilError(t, err)
+	job, err := recording.GetJob(5985)
+	assert.NilError(t, err)
+	_, err = recording.GetJob(4242)
+	assert.Assert(t, err != nil, "fetching a non-existing job should fail")
+
+	// Credentials must not end up in the fixtures
+	buf, err := os.ReadFile(filepath.Join(dir, "api", "v1", "jobs", "5830", "comments", "GET.json"))
+	assert.NilError(t, err)
+	assert.Assert(t, !strings.Contains(string(buf), "1234567890ABCDEF"))
+	assert.Assert(t, strings.Contains(string(buf), REDACTED))
+
+	// Replay must not contact the server
+	replay := CreateInstance("http://replay.invalid")
+	replay.Replay(dir)
+	replayed, err := replay.GetComments(COMMENT_TEST_JOB_ID)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, replayed, comments)
+	replayedJob, err := replay.GetJob(5985)
+	assert.NilError(t, err)
+	assert.Equal(t, replayedJob.Name, job.Name)
+	_, err = replay.GetJob(4242)
+	assert.Assert(t, err != nil, "replaying a failed request should fail")
+	_, err = replay.GetJob(5991)
+	assert.ErrorContains(t, err, "no fixture for GET /api/v1/jobs/5991")
+
+	// The order of query parameters does not matter
+	status, recordedBody, err := recording.Do("GET", "/api/v1/jobs/5985?groupid=7&build=20230101&ids=1&ids=2", "", nil)
+	assert.NilError(t, err)
+	assert.Equal(t, status, 200)
+	status, replayedBody, err := replay.Do("GET", "/api/v1/jobs/5985?ids=1&build=20230101&ids=2&groupid=7", "", nil)
+	assert.NilError(t, err)
+	assert.Equal(t, status, 200)
+	assert.DeepEqual(t, replayedBody, recordedBody)
+	_, _, err = replay.Do("GET", "/api/v1/jobs/5985?groupid=7&build=20230101&ids=2&ids=1", "", nil)
+	assert.ErrorContains(t, err, "no fixture for GET /api/v1/jobs/5985")
+}
+
+/* AMQP connection and channel, which record the declarations of subscriptions */
+type recordingAMQP struct {
+	mutex      sync.Mutex
+	closed     bool
+	prefetch   int
+	queues     []string // name, durable, auto-delete and exclusive flag of the declared queues
+	bindings   []string // exchange/key->queue
+	consumed   []string // queue and auto-ack flag
+	deliveries []chan amqp.Delivery
+}
+
+func (r *recordingAMQP) Channel() (AMQPChannel, error) { return r, nil }
+func (r *recordingAMQP) IsClosed() bool {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return r.closed
+}
+func (r *recordingAMQP) NotifyClose(receiver chan *amqp.Error) chan *amqp.Error { return receiver }
+func (r *recordingAMQP) Qos(prefetchCount, prefetchSize int, global bool) error {
+	r.prefetch = prefetchCount
+	return nil
+}
+func (r *recordingAMQP) QueueDeclare(name string, durable, autoDelete, exclusive, noWait bool, args amqp.Table) (amqp.Queue, error) {
+	r.queues = append(r.queues, fmt.Sprintf("%s durable=%v auto-delete=%v exclusive=%v", name, durable, autoDelete, exclusive))
+	if name == "" {
+		name = "amq.gen-1"
+	}
+	return amqp.Queue{Name: name}, nil
+}
+func (r *recordingAMQP) QueueBind(name, key, exchange string, noWait bool, args amqp.Table) error {
+	r.bindings = append(r.bindings, fmt.Sprintf("%s/%s->%s", exchange, key, name))
+	return nil
+}
+func (r *recordingAMQP) Consume(queue, consumer string, autoAck, exclusive, noLocal, noWait bool, args amqp.Table) (<-chan amqp.Delivery, error) {
+	r.consumed = append(r.consumed, fmt.Sprintf("%s auto-ack=%v", queue, autoAck))
+	deliveries := make(chan amqp.Delivery)
+	r.deliveries = append(r.deliveries, deliveries)
+	return deliveries, nil
+}
+func (r *recordingAMQP) Close() error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if !r.closed {
+		r.closed = true
+		for _, deliveries := range r.deliveries {
+			close(deliveries)
+		}
+	}
+	return nil
+}
+
+func TestSubscribeOptions(t *testing.T) {
+	tests := []struct {
+		name     string
+		opts     RabbitMQSubscribeOptions
+		prefetch int
+		queues   []string
+		bindings []string
+		consumed []string
+	}{
+		{"default", RabbitMQSubscribeOptions{Keys: []string{"suse.openqa.job.done"}}, 0,
+			[]string{" durable=false auto-delete=false exclusive=true"},
+			[]string{"pubsub/suse.openqa.job.done->amq.gen-1"},
+			[]string{"amq.gen-1 auto-ack=true"}},
+		{"durable queue", RabbitMQSubscribeOptions{Keys: []string{"suse.openqa.job.*", "suse.openqa.comment.#"}, Exchange: "openqa", Queue: "gopenqa", Durable: true, Prefetch: 10, ManualAck: true}, 10,
+			[]string{"gopenqa durable=true auto-delete=false exclusive=false"},
+			[]string{"openqa/suse.openqa.job.*->gopenqa", "openqa/suse.openqa.comment.#->gopenqa"},
+			[]string{"gopenqa auto-ack=false"}},
+		{"auto-delete queue", RabbitMQSubscribeOptions{Keys: []string{"#"}, Exchange: "amq.topic", Queue: "monitor", AutoDelete: true}, 0,
+			[]string{"monitor durable=false auto-delete=true exclusive=false"},
+			[]string{"amq.topic/#->monitor"},
+			[]string{"monitor auto-ack=true"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			con := &recordingAMQP{}
+			mq, err := ConnectRabbitMQWithDialer("amqp://recording", func(remote string) (AMQPConnection, error) { return con, nil })
+			assert.NilError(t, err)
+			defer mq.Close()
+			sub, err := mq.SubscribeWithOptions(test.opts)
+			assert.NilError(t, err)
+			defer sub.Close()
+			assert.Equal(t, con.prefetch, test.prefetch)
+			assert.DeepEqual(t, con.queues, test.queues)
+			assert.DeepEqual(t, con.bindings, test.bindings)
+			assert.DeepEqual(t, con.consumed, test.consumed)
+		})
+	}
+
+	mq, err := ConnectRabbitMQWithDialer("amqp://recording", func(remote string) (AMQPConnection, error) { return &recordingAMQP{}, nil })
+	assert.NilError(t, err)
+	defer mq.Close()
+	_, err = mq.SubscribeWithOptions(RabbitMQSubscribeOptions{Queue: "gopenqa"})
+	assert.ErrorContains(t, err, "no binding keys")
+}
+
+func TestWriteArchive(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "backup.tar.gz")
+	// Names with NUL characters cannot be encoded, so that writing fails after the first file
+	err := writeArchive(path, map[string][]byte{"a.json": []byte("{}"), "b\x00.json": []byte("{}")})
+	assert.ErrorContains(t, err, "archive/tar")
+	entries, err := os.ReadDir(dir)
+	assert.NilError(t, err)
+	assert.Equal(t, len(entries), 0, "a failed archive should leave no files behind")
+
+	assert.NilError(t, writeArchive(path, map[string][]byte{"a.json": []byte("{}")}))
+	info, err := os.Stat(path)
+	assert.NilError(t, err)
+	assert.Equal(t, info.Mode().Perm(), os.FileMode(0644))
+	assert.ErrorContains(t, writeArchive(path, map[string][]byte{}), "already exists")
+	entries, err = os.ReadDir(dir)
+	assert.NilError(t, err)
+	assert.Equal(t, len(entries), 1)
+}
+
+func TestRabbitMQZeroValue(t *testing.T) {
+	// RabbitMQ objects not created via ConnectRabbitMQ must not panic
+	var mq RabbitMQ
+	assert.Assert(t, !mq.Connected())
+	assert.Assert(t, mq.Closed())
+	mq.SetReconnectBackoff(time.Millisecond, time.Second)
+	mq.Close()
+	assert.Assert(t, mq.closedByUser())
+	mq.NotifyClose(func(error) {})
+	_, err := mq.connection()
+	assert.Error(t, err, "connection closed")
+}
+
+// countingTransport counts the requests and the responses with 304 Not Modified
+type countingTransport struct {
+	requests    int
+	notModified int
+}
+
+func (c *countingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := http.DefaultTransport.RoundTrip(req)
+	c.requests++
+	if err == nil && resp.StatusCode == http.StatusNotModified {
+		c.notModified++
+	}
+	return resp, err
+}
+
+func TestCache(t *testing.T) {
+	dir := t.TempDir()
+	transport := &countingTransport{}
+	cache := NewCache(2, dir)
+	cached := CreateInstance(instance.URL)
+	cached.SetTransport(transport)
+	cached.SetCache(cache)
+
+	// Machines are cached for some time
+	machines, err := cached.GetMachines()
+	assert.NilError(t, err)
+	cachedMachines, err := cached.GetMachines()
+	assert.NilError(t, err)
+	assert.DeepEqual(t, cachedMachines, machines)
+	assert.Equal(t, transport.requests, 1)
+
+	// Finished jobs never expire
+	job, err := cached.GetJob(5985)
+	assert.NilError(t, err)
+	_, err = cached.GetJob(5985)
+	assert.NilError(t, err)
+	assert.Equal(t, transport.requests, 2)
+
+	// Workers are revalidated via If-Modified-Since on each request
+	_, err = cached.GetWorkers()
+	assert.NilError(t, err)
+	workers, err := cached.GetWorkers()
+	assert.NilError(t, err)
+	assert.Equal(t, len(workers), 2)
+	assert.Equal(t, transport.requests, 4)
+	assert.Equal(t, transport.notModified, 1)
+
+	// Least recently used entries are evicted from memory, but kept on disk
+	assert.Equal(t, cache.Len(), 2)
+	_, err = cached.GetMachines()
+	assert.NilError(t, err)
+	assert.Equal(t, transport.requests, 4)
+
+	// Entries survive on disk
+	reopened := CreateInstance(instance.URL)
+	reopened.SetTransport(transport)
+	reopened.SetCache(NewCache(10, dir))
+	reopenedJob, err := reopened.GetJob(5985)
+	assert.NilError(t, err)
+	assert.Equal(t, reopenedJob.Name, job.Name)
+	assert.Equal(t, transport.requests, 4)
+
+	// Writes invalidate their endpoint and the endpoints depending on it
+	// The test server serves files only, but the request invalidates the cache nevertheless
+	cached.DeleteJob(5985)
+	_, err = cached.GetJob(5985)
+	assert.NilError(t, err)
+	_, err = cached.GetMachines()
+	assert.NilError(t, err)
+	assert.Equal(t, transport.requests, 6)
+	cached.Do("DELETE", "/api/v1/machines/1", "", nil)
+	_, err = cached.GetMachines()
+	assert.NilError(t, err)
+	_, err = cached.GetJob(5985)
+	assert.NilError(t, err)
+	assert.Equal(t, transport.requests, 8)
+	for _, url := range []string{"/api/v1/job_templates", "/api/v1/job_templates_scheduling/1", "/api/v1/job_groups/1", "/api/v1/parent_groups", "/api/v1/products"} {
+		cache.store(cacheEntry{Key: url, Endpoint: cacheEndpoint(url), Expires: time.Now().Add(time.Hour)})
+	}
+	cache.invalidateWrite("/api/v1/job_groups/1")
+	for url, cached := range map[string]bool{"/api/v1/job_templates": false, "/api/v1/job_templates_scheduling/1": false, "/api/v1/job_groups/1": false, "/api/v1/parent_groups": false, "/api/v1/products": true} {
+		_, ok := cache.lookup(url, cacheEndpoint(url))
+		assert.Equal(t, ok, cached, url)
+	}
+
+	// Entries of earlier runs are invalidated without reading them
+	assert.NilError(t, os.WriteFile(filepath.Join(dir, filename("jobs", "/api/v1/jobs/1")), []byte("invalid"), 0644))
+	NewCache(10, dir).Invalidate("jobs")
+	files, err := filepath.Glob(filepath.Join(dir, "jobs-*.json"))
+	assert.NilError(t, err)
+	assert.Equal(t, len(files), 0)
+	files, err = filepath.Glob(filepath.Join(dir, "machines-*.json"))
+	assert.NilError(t, err)
+	assert.Equal(t, len(files), 1)
+
+	cache.Invalidate("")
+	assert.Equal(t, cache.Len(), 0)
+	reopened.SetCache(NewCache(10, dir))
+	_, err = reopened.GetJob(5985)
+	assert.NilError(t, err)
+	_, err = reopened.GetMachines()
+	assert.NilError(t, err)
+	assert.Equal(t, transport.requests, 10)
+	entries, err := os.ReadDir(dir)
+	assert.NilError(t, err)
+	assert.Equal(t, len(entries), 2)
+
+	// Following clones revalidates finished jobs, as they might have been restarted
+	notModified := transport.notModified
+	_, err = reopened.GetJobFollow(5985)
+	assert.NilError(t, err)
+	assert.Equal(t, transport.requests, 11)
+	assert.Equal(t, transport.notModified, notModified+1)
+	_, err = reopened.GetJob(5985)
+	assert.NilError(t, err)
+	assert.Equal(t, transport.requests, 11)
+
+	// Entries are kept per API key, without storing the key
+	reopened.SetApiKey("1234567890ABCDEF", "FEDCBA0987654321")
+	_, err = reopened.GetMachines()
+	assert.NilError(t, err)
+	_, err = reopened.GetMachines()
+	assert.NilError(t, err)
+	assert.Equal(t, transport.requests, 12)
+	reopened.SetApiKey("", "")
+	_, err = reopened.GetMachines()
+	assert.NilError(t, err)
+	assert.Equal(t, transport.requests, 12)
+	entries, err = os.ReadDir(dir)
+	assert.NilError(t, err)
+	assert.Equal(t, len(entries), 3)
+	for _, entry := range entries {
+		buf, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+		assert.NilError(t, err)
+		assert.Assert(t, !strings.Contains(string(buf), "1234567890ABCDEF"))
+	}
+}
@@ -0,0 +1,393 @@
+package gopenqatest
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"github.com/os-autoinst/gopenqa"
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// Default exchange openQA publishes to
+const DefaultExchange = "pubsub"
+
+// Number of messages a queue can hold before publishing blocks
+const queueSize = 1024
+
+/* Broker is an in-memory AMQP broker, which implements the topic exchange semantics used by openQA.
+ * Use Connect to get a RabbitMQ connection to the broker and Publish to send messages to all bound queues */
+type Broker struct {
+	mutex       sync.Mutex
+	offline     bool // If true, dialing fails
+	queues      map[string]*brokerQueue
+	connections []*brokerConnection
+	queueIDs    int
+	acks        int
+	nacks       int
+}
+
+type brokerBinding struct {
+	exchange string
+	key      string
+}
+
+type brokerQueue struct {
+	name       string
+	durable    bool
+	autoDelete bool
+	exclusive  bool
+	bindings   []brokerBinding
+	messages   chan amqp.Delivery
+}
+
+type brokerConnection struct {
+	broker   *Broker
+	mutex    sync.Mutex
+	closed   bool
+	channels []*brokerChannel
+	notify   []chan *amqp.Error
+}
+
+type brokerChannel struct {
+	con     *brokerConnection
+	mutex   sync.Mutex
+	closed  bool
+	done    chan struct{}
+	queues  []*brokerQueue
+	tags    uint64
+	unacked map[uint64]unackedDelivery
+}
+
+type unackedDelivery struct {
+	queue    *brokerQueue
+	delivery amqp.Delivery
+}
+
+/* NewBroker creates a new in-memory broker without any queues */
+func NewBroker() *Broker {
+	return &Broker{queues: make(map[string]*brokerQueue, 0)}
+}
+
+/* Dial connects to the broker. The remote is ignored. This is an gopenqa.AMQPDialer */
+func (b *Broker) Dial(remote string) (gopenqa.AMQPConnection, error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	if b.offline {
+		return nil, fmt.Errorf("dial %s: connection refused", remote)
+	}
+	con := &brokerConnection{broker: b}
+	b.connections = append(b.connections, con)
+	return con, nil
+}
+
+/* Connect returns a RabbitMQ connection to this broker. Reconnects of subscriptions also go to this broker */
+func (b *Broker) Connect() (gopenqa.RabbitMQ, error) {
+	return gopenqa.ConnectRabbitMQWithDialer("amqp://gopenqatest", b.Dial)
+}
+
+/* SetOffline makes the broker refuse new connections, e.g. to test reconnection attempts */
+func (b *Broker) SetOffline(offline bool) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.offline = offline
+}
+
+/* Disconnect closes all connections with an error, like a broker restart does. Durable queues and their messages are kept */
+func (b *Broker) Disconnect() {
+	b.mutex.Lock()
+	connections := b.connections
+	b.connections = make([]*brokerConnection, 0)
+	b.mutex.Unlock()
+	for _, con := range connections {
+		con.shutdown(&amqp.Error{Code: amqp.ConnectionForced, Reason: "CONNECTION_FORCED - broker forced connection closure", Server: true})
+	}
+}
+
+/* Publish sends the given message to the default exchange with the given routing key. Returns the number of queues the message has been routed to */
+func (b *Broker) Publish(key string, body []byte) int {
+	return b.PublishExchange(DefaultExchange, key, body)
+}
+
+/* PublishJSON encodes the given payload as JSON and publishes it with the given routing key */
+func (b *Broker) PublishJSON(key string, payload interface{}) (int, error) {
+	buf, err := json.Marshal(payload)
+	if err != nil {
+		return 0, err
+	}
+	return b.Publish(key, buf), nil
+}
+
+/* PublishExchange sends the given message to the given exchange. Returns the number of queues the message has been routed to */
+func (b *Broker) PublishExchange(exchange string, key string, body []byte) int {
+	b.mutex.Lock()
+	queues := make([]*brokerQueue, 0)
+	for _, q := range b.queues {
+		for _, binding := range q.bindings {
+			if binding.exchange == exchange && gopenqa.MatchRoutingKey(binding.key, key) {
+				queues = append(queues, q)
+				break
+			}
+		}
+	}
+	b.mutex.Unlock()
+	for _, q := range queues {
+		q.messages <- amqp.Delivery{Exchange: exchange, RoutingKey: key, Body: body, ContentType: "application/json"}
+	}
+	return len(queues)
+}
+
+/* Queues returns the names of all declared queues */
+func (b *Broker) Queues() []string {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	ret := make([]string, 0)
+	for name := range b.queues {
+		ret = append(ret, name)
+	}
+	return ret
+}
+
+/* Acks returns the number of acknowledged messages */
+func (b *Broker) Acks() int {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.acks
+}
+
+/* Nacks returns the number of rejected messages */
+func (b *Broker) Nacks() int {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.nacks
+}
+
+func (b *Broker) deleteQueue(q *brokerQueue) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	if b.queues[q.name] == q {
+		delete(b.queues, q.name)
+	}
+}
+
+/* Connection */
+
+func (con *brokerConnection) Channel() (gopenqa.AMQPChannel, error) {
+	con.mutex.Lock()
+	defer con.mutex.Unlock()
+	if con.closed {
+		return nil, amqp.ErrClosed
+	}
+	ch := &brokerChannel{con: con, done: make(chan struct{}), unacked: make(map[uint64]unackedDelivery, 0)}
+	con.channels = append(con.channels, ch)
+	return ch, nil
+}
+
+func (con *brokerConnection) IsClosed() bool {
+	con.mutex.Lock()
+	defer con.mutex.Unlock()
+	return con.closed
+}
+
+func (con *brokerConnection) Close() error {
+	con.shutdown(nil)
+	return nil
+}
+
+func (con *brokerConnection) NotifyClose(receiver chan *amqp.Error) chan *amqp.Error {
+	con.mutex.Lock()
+	defer con.mutex.Unlock()
+	if con.closed {
+		close(receiver)
+	} else {
+		con.notify = append(con.notify, receiver)
+	}
+	return receiver
+}
+
+// shutdown closes the connection and all its channels. Listeners are notified about the error, if present
+func (con *brokerConnection) shutdown(err *amqp.Error) {
+	con.mutex.Lock()
+	if con.closed {
+		con.mutex.Unlock()
+		return
+	}
+	con.closed = true
+	channels := con.channels
+	notify := con.notify
+	con.notify = nil
+	con.mutex.Unlock()
+
+	for _, ch := range channels {
+		ch.Close()
+	}
+	for _, receiver := range notify {
+		if err != nil {
+			receiver <- err
+		}
+		close(receiver)
+	}
+}
+
+/* Channel */
+
+func (ch *brokerChannel) Qos(prefetchCount, prefetchSize int, global bool) error {
+	return nil
+}
+
+func (ch *brokerChannel) QueueDeclare(name string, durable, autoDelete, exclusive, noWait bool, args amqp.Table) (amqp.Queue, error) {
+	b := ch.con.broker
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	if name == "" {
+		b.queueIDs++
+		name = fmt.Sprintf("amq.gen-%d", b.queueIDs)
+	}
+	q, ok := b.queues[name]
+	if !ok {
+		q = &brokerQueue{name: name, durable: durable, autoDelete: autoDelete, exclusive: exclusive, messages: make(chan amqp.Delivery, queueSize)}
+		b.queues[name] = q
+	}
+	ch.mutex.Lock()
+	ch.queues = append(ch.queues, q)
+	ch.mutex.Unlock()
+	return amqp.Queue{Name: name, Messages: len(q.messages)}, nil
+}
+
+func (ch *brokerChannel) QueueBind(name, key, exchange string, noWait bool, args amqp.Table) error {
+	b := ch.con.broker
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	q, ok := b.queues[name]
+	if !ok {
+		return fmt.Errorf("queue %s not found", name)
+	}
+	for _, binding := range q.bindings {
+		if binding.exchange == exchange && binding.key == key {
+			return nil
+		}
+	}
+	q.bindings = append(q.bindings, brokerBinding{exchange: exchange, key: key})
+	return nil
+}
+
+func (ch *brokerChannel) Consume(queue, consumer string, autoAck, exclusive, noLocal, noWait bool, args amqp.Table) (<-chan amqp.Delivery, error) {
+	b := ch.con.broker
+	b.mutex.Lock()
+	q, ok := b.queues[queue]
+	b.mutex.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("queue %s not found", queue)
+	}
+	deliveries := make(chan amqp.Delivery)
+	go ch.forward(q, deliveries, autoAck)
+	return deliveries, nil
+}
+
+// forward delivers the messages of the queue to the consumer until the channel is closed
+func (ch *brokerChannel) forward(q *brokerQueue, deliveries chan amqp.Delivery, autoAck bool) {
+	defer close(deliveries)
+	for {
+		select {
+		case d := <-q.messages:
+			ch.mutex.Lock()
+			if ch.closed {
+				// Picked up a message returned by Close, keep it in the queue
+				ch.mutex.Unlock()
+				q.messages <- d
+				return
+			}
+			ch.tags++
+			d.DeliveryTag = ch.tags
+			d.Acknowledger = ch
+			if !autoAck {
+				ch.unacked[d.DeliveryTag] = unackedDelivery{queue: q, delivery: d}
+			}
+			ch.mutex.Unlock()
+			select {
+			case deliveries <- d:
+			case <-ch.done:
+				// Not delivered, keep the message in the queue
+				ch.mutex.Lock()
+				delete(ch.unacked, d.DeliveryTag)
+				ch.mutex.Unlock()
+				d.Acknowledger = nil
+				q.messages <- d
+				return
+			}
+		case <-ch.done:
+			return
+		}
+	}
+}
+
+func (ch *brokerChannel) Close() error {
+	ch.mutex.Lock()
+	if ch.closed {
+		ch.mutex.Unlock()
+		return nil
+	}
+	ch.closed = true
+	close(ch.done)
+	queues := ch.queues
+	unacked := ch.unacked
+	ch.unacked = make(map[uint64]unackedDelivery, 0)
+	ch.mutex.Unlock()
+
+	// Unacknowledged messages are returned to their queues
+	for _, u := range unacked {
+		u.delivery.Acknowledger = nil
+		u.delivery.Redelivered = true
+		u.queue.messages <- u.delivery
+	}
+	for _, q := range queues {
+		if q.exclusive || q.autoDelete {
+			ch.con.broker.deleteQueue(q)
+		}
+	}
+	return nil
+}
+
+/* Acknowledger, used for the deliveries of the channel */
+
+func (ch *brokerChannel) take(tag uint64, multiple bool) []unackedDelivery {
+	ch.mutex.Lock()
+	defer ch.mutex.Unlock()
+	ret := make([]unackedDelivery, 0)
+	for t, u := range ch.unacked {
+		if t == tag || (multiple && t < tag) {
+			ret = append(ret, u)
+			delete(ch.unacked, t)
+		}
+	}
+	return ret
+}
+
+func (ch *brokerChannel) Ack(tag uint64, multiple bool) error {
+	acked := ch.take(tag, multiple)
+	b := ch.con.broker
+	b.mutex.Lock()
+	b.acks += len(acked)
+	b.mutex.Unlock()
+	return nil
+}
+
+func (ch *brokerChannel) Nack(tag uint64, multiple bool, requeue bool) error {
+	nacked := ch.take(tag, multiple)
+	b := ch.con.broker
+	b.mutex.Lock()
+	b.nacks += len(nacked)
+	b.mutex.Unlock()
+	if requeue {
+		for _, u := range nacked {
+			u.delivery.Acknowledger = nil
+			u.delivery.Redelivered = true
+			u.queue.messages <- u.delivery
+		}
+	}
+	return nil
+}
+
+func (ch *brokerChannel) Reject(tag uint64, requeue bool) error {
+	return ch.Nack(tag, false, requeue)
+}
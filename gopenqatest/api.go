@@ -0,0 +1,855 @@
+package gopenqatest
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/os-autoinst/gopenqa"
+)
+
+var bugrefRegex = regexp.MustCompile(`\b(?:poo|bsc|boo|bnc|gh|kde|fdo|jsc|lp|rh)#[\w/.#-]*\w`)
+
+/* A single API request, as passed to the handlers */
+type request struct {
+	method string
+	path   []string // Path components after /api/v1
+	params url.Values
+	body   []byte
+	user   string // Authenticated user, only set for modifying requests
+}
+
+// rawResponse is sent as-is instead of being encoded as JSON
+type rawResponse string
+
+// Handlers return the HTTP status code and the response, which is encoded as JSON
+type handler func(req *request) (int, interface{})
+
+func failure(status int, format string, args ...interface{}) (int, interface{}) {
+	return status, map[string]interface{}{"error": fmt.Sprintf(format, args...), "error_status": status}
+}
+
+func notFound() (int, interface{}) {
+	return failure(http.StatusNotFound, "Not found")
+}
+
+func methodNotAllowed(req *request) (int, interface{}) {
+	return failure(http.StatusMethodNotAllowed, "Method %s not supported", req.method)
+}
+
+func bugrefs(text string) []string {
+	refs := bugrefRegex.FindAllString(text, -1)
+	if refs == nil {
+		return make([]string, 0)
+	}
+	return refs
+}
+
+// Split the path into its non-empty components. openQA tolerates duplicate slashes
+func splitPath(path string) []string {
+	ret := make([]string, 0)
+	for _, c := range strings.Split(path, "/") {
+		if c != "" {
+			ret = append(ret, c)
+		}
+	}
+	return ret
+}
+
+func (req *request) param(name string) string {
+	return req.params.Get(name)
+}
+
+// values returns all values of the given parameter. Comma separated values are split
+func (req *request) values(name string) []string {
+	ret := make([]string, 0)
+	for _, value := range req.params[name] {
+		for _, v := range strings.Split(value, ",") {
+			if v != "" {
+				ret = append(ret, v)
+			}
+		}
+	}
+	return ret
+}
+
+func (req *request) intParam(name string) int {
+	i, _ := strconv.Atoi(req.param(name))
+	return i
+}
+
+// settings returns all parameters given as settings[KEY]=VALUE
+func (req *request) settings() map[string]string {
+	ret := make(map[string]string, 0)
+	for k, v := range req.params {
+		if strings.HasPrefix(k, "settings[") && strings.HasSuffix(k, "]") && len(v) > 0 {
+			ret[k[len("settings["):len(k)-1]] = v[0]
+		}
+	}
+	return ret
+}
+
+func (req *request) id(i int) (int64, bool) {
+	if i >= len(req.path) {
+		return 0, false
+	}
+	id, err := strconv.ParseInt(req.path[i], 10, 64)
+	return id, err == nil && id > 0
+}
+
+// encodeSettings encodes the given settings as [{"key":...,"value":...}] list, as openQA does for machines, products and test suites
+func encodeSettings(settings map[string]string) []map[string]string {
+	keys := make([]string, 0)
+	for k := range settings {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	ret := make([]map[string]string, 0)
+	for _, k := range keys {
+		ret = append(ret, map[string]string{"key": k, "value": settings[k]})
+	}
+	return ret
+}
+
+func (s *Server) serve(w http.ResponseWriter, r *http.Request) {
+	s.mutex.Lock()
+	s.requests = append(s.requests, r.Method+" "+r.URL.Path)
+	s.mutex.Unlock()
+
+	req := request{method: r.Method, params: r.URL.Query()}
+	status, response := s.handle(r, &req)
+
+	if raw, ok := response.(rawResponse); ok {
+		w.Header().Set("Content-Type", "text/plain")
+		w.WriteHeader(status)
+		w.Write([]byte(raw))
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(response)
+}
+
+func (s *Server) handle(r *http.Request, req *request) (int, interface{}) {
+	var err error
+	if req.body, err = io.ReadAll(r.Body); err != nil {
+		return failure(http.StatusBadRequest, "%s", err)
+	}
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "application/x-www-form-urlencoded") {
+		// Invalid form data (e.g. YAML sent as raw body) is ignored
+		if form, err := url.ParseQuery(string(req.body)); err == nil {
+			for k, v := range form {
+				req.params[k] = append(req.params[k], v...)
+			}
+		}
+	}
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		if req.user, err = s.authenticate(r); err != nil {
+			return failure(http.StatusForbidden, "%s", err)
+		}
+	}
+
+	path := splitPath(r.URL.Path)
+	if len(path) < 3 || path[0] != "api" || path[1] != "v1" {
+		return notFound()
+	}
+	req.path = path[2:]
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	handlers := map[string]handler{
+		"jobs":                     s.handleJobs,
+		"experimental":             s.handleExperimental,
+		"job_groups":               s.handleJobGroups,
+		"parent_groups":            s.handleParentGroups,
+		"groups":                   s.handleGroups,
+		"machines":                 s.handleMachines,
+		"products":                 s.handleProducts,
+		"test_suites":              s.handleTestSuites,
+		"job_templates":            s.handleJobTemplates,
+		"job_templates_scheduling": s.handleScheduling,
+		"isos":                     s.handleIsos,
+		"workers":                  s.handleWorkers,
+	}
+	if h, ok := handlers[req.path[0]]; ok {
+		return h(req)
+	}
+	return notFound()
+}
+
+/* Jobs */
+
+// matchJob checks if the given job matches the job query parameters
+func matchJob(job gopenqa.Job, req *request) bool {
+	if ids := req.values("ids"); len(ids) > 0 {
+		found := false
+		for _, id := range ids {
+			if id == fmt.Sprintf("%d", job.ID) {
+				found = true
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	checks := map[string]string{
+		"state":   job.State,
+		"result":  job.Result,
+		"test":    job.Test,
+		"arch":    job.Settings.Arch,
+		"machine": job.Settings.Machine,
+		"groupid": fmt.Sprintf("%d", job.GroupID),
+		"build":   job.Settings.Build,
+		"distri":  job.Settings.Distri,
+		"version": job.Settings.Version,
+		"flavor":  job.Settings.Flavor,
+	}
+	for param, value := range checks {
+		if accepted := req.values(param); len(accepted) > 0 {
+			found := false
+			for _, v := range accepted {
+				if v == value {
+					found = true
+				}
+			}
+			if !found {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// queryJobs returns the jobs matching the query parameters, sorted by ID
+func (s *Server) queryJobs(req *request) []gopenqa.Job {
+	jobs := make([]gopenqa.Job, 0)
+	for _, job := range s.sortedJobs() {
+		if matchJob(job, req) {
+			jobs = append(jobs, job)
+		}
+	}
+	if req.param("latest") == "1" {
+		// Only keep the most recent job per test, arch and machine
+		latest := make(map[string]gopenqa.Job, 0)
+		for _, job := range jobs {
+			latest[job.Test+"@"+job.Settings.Arch+"@"+job.Settings.Machine] = job
+		}
+		jobs = make([]gopenqa.Job, 0)
+		for _, job := range latest {
+			jobs = append(jobs, job)
+		}
+		sort.Slice(jobs, func(i, j int) bool { return jobs[i].ID < jobs[j].ID })
+	}
+	if limit := req.intParam("limit"); limit > 0 && len(jobs) > limit {
+		jobs = jobs[len(jobs)-limit:]
+	}
+	return jobs
+}
+
+func (s *Server) handleJobs(req *request) (int, interface{}) {
+	if len(req.path) == 1 {
+		if req.method == http.MethodGet {
+			return http.StatusOK, map[string]interface{}{"jobs": s.queryJobs(req)}
+		} else if req.method == http.MethodPost {
+			job := s.createJob(req.params)
+			return http.StatusOK, map[string]interface{}{"id": job.ID}
+		}
+		return methodNotAllowed(req)
+	}
+	if req.path[1] == "overview" && req.method == http.MethodGet {
+		req.params.Set("latest", "1")
+		overview := make([]map[string]interface{}, 0)
+		for _, job := range s.queryJobs(req) {
+			overview = append(overview, map[string]interface{}{"id": job.ID, "name": job.Name})
+		}
+		return http.StatusOK, overview
+	}
+
+	id, ok := req.id(1)
+	if !ok {
+		return notFound()
+	}
+	job, ok := s.jobs[id]
+	if !ok {
+		return failure(http.StatusNotFound, "Job does not exist")
+	}
+	if len(req.path) == 2 {
+		switch req.method {
+		case http.MethodGet:
+			return http.StatusOK, map[string]interface{}{"job": job}
+		case http.MethodDelete:
+			delete(s.jobs, id)
+			delete(s.jobComments, id)
+			return http.StatusOK, map[string]interface{}{"result": 1}
+		}
+		return methodNotAllowed(req)
+	}
+
+	switch req.path[2] {
+	case "comments":
+		comments := s.jobComments[id]
+		status, response, updated := s.handleComments(req, comments, 3)
+		s.jobComments[id] = updated
+		return status, response
+	case "restart":
+		if req.method != http.MethodPost {
+			return methodNotAllowed(req)
+		}
+		clone := job
+		clone.ID = 0
+		clone.CloneID = 0
+		clone.State = "scheduled"
+		clone.Result = "none"
+		clone.Tstarted = ""
+		clone.Tfinished = ""
+		clone = s.addJob(clone)
+		job.CloneID = clone.ID
+		s.jobs[id] = job
+		return http.StatusOK, map[string]interface{}{
+			"result":   []map[string]int64{{fmt.Sprintf("%d", id): clone.ID}},
+			"test_url": []map[string]string{{fmt.Sprintf("%d", id): fmt.Sprintf("/tests/%d", clone.ID)}},
+		}
+	case "cancel":
+		if req.method != http.MethodPost {
+			return methodNotAllowed(req)
+		}
+		if job.State != "done" && job.State != "cancelled" {
+			job.State = "cancelled"
+			job.Result = "user_cancelled"
+			job.Tfinished = time.Now().UTC().Format("2006-01-02T15:04:05")
+			s.jobs[id] = job
+		}
+		return http.StatusOK, map[string]interface{}{"result": 1}
+	}
+	return notFound()
+}
+
+// createJob creates a new scheduled job from the given settings
+func (s *Server) createJob(params url.Values) gopenqa.Job {
+	var job gopenqa.Job
+	job.Test = params.Get("TEST")
+	job.Settings.Arch = params.Get("ARCH")
+	job.Settings.Machine = params.Get("MACHINE")
+	job.Settings.Backend = params.Get("BACKEND")
+	job.Settings.Build = params.Get("BUILD")
+	job.Settings.Distri = params.Get("DISTRI")
+	job.Settings.Flavor = params.Get("FLAVOR")
+	job.Settings.Version = params.Get("VERSION")
+	job.Priority, _ = strconv.Atoi(params.Get("_PRIORITY"))
+	if job.Priority == 0 {
+		job.Priority = 50
+	}
+	job.GroupID, _ = strconv.Atoi(params.Get("_GROUP_ID"))
+	if name := params.Get("_GROUP"); name != "" {
+		for _, group := range s.jobGroups {
+			if group.Name == name {
+				job.GroupID = group.ID
+			}
+		}
+	}
+	job.Result = "none"
+	parts := make([]string, 0)
+	for _, key := range []string{"DISTRI", "VERSION", "FLAVOR", "ARCH"} {
+		if value := params.Get(key); value != "" {
+			parts = append(parts, value)
+		}
+	}
+	if build := params.Get("BUILD"); build != "" {
+		parts = append(parts, "Build"+build)
+	}
+	parts = append(parts, job.Test)
+	job.Name = strings.Join(parts, "-")
+	if job.Settings.Machine != "" {
+		job.Name += "@" + job.Settings.Machine
+	}
+	return s.addJob(job)
+}
+
+func (s *Server) handleExperimental(req *request) (int, interface{}) {
+	// Only /experimental/jobs/ID/status is supported
+	if len(req.path) != 4 || req.path[1] != "jobs" || req.path[3] != "status" || req.method != http.MethodGet {
+		return notFound()
+	}
+	id, ok := req.id(2)
+	if !ok {
+		return notFound()
+	}
+	job, ok := s.jobs[id]
+	if !ok {
+		return failure(http.StatusNotFound, "Job does not exist")
+	}
+	return http.StatusOK, map[string]interface{}{"blocked_by_id": job.BlockedByID, "result": job.Result, "state": job.State}
+}
+
+/* Comments */
+
+// handleComments handles the comment requests, where path[i] is the "comments" component. Returns the updated comments
+func (s *Server) handleComments(req *request, comments []gopenqa.Comment, i int) (int, interface{}, []gopenqa.Comment) {
+	if comments == nil {
+		comments = make([]gopenqa.Comment, 0)
+	}
+	if len(req.path) == i {
+		switch req.method {
+		case http.MethodGet:
+			return http.StatusOK, comments, comments
+		case http.MethodPost:
+			text := req.param("text")
+			if text == "" {
+				status, response := failure(http.StatusBadRequest, "Missing parameter 'text'")
+				return status, response, comments
+			}
+			comment := s.newComment(gopenqa.Comment{Text: text, User: req.user})
+			return http.StatusOK, map[string]interface{}{"id": comment.ID}, append(comments, comment)
+		}
+		status, response := methodNotAllowed(req)
+		return status, response, comments
+	}
+	cid, _ := req.id(i)
+	for j, comment := range comments {
+		if int64(comment.ID) != cid {
+			continue
+		}
+		switch req.method {
+		case http.MethodGet:
+			return http.StatusOK, comment, comments
+		case http.MethodPut:
+			comment.Text = req.param("text")
+			comment.BugRefs = bugrefs(comment.Text)
+			comment.Updated = time.Now().UTC().Format(timeFormat)
+			comments[j] = comment
+			return http.StatusOK, map[string]interface{}{"id": comment.ID}, comments
+		case http.MethodDelete:
+			comments = append(comments[:j], comments[j+1:]...)
+			return http.StatusOK, map[string]interface{}{"id": comment.ID}, comments
+		}
+		status, response := methodNotAllowed(req)
+		return status, response, comments
+	}
+	status, response := failure(http.StatusNotFound, "Comment %d does not exist", cid)
+	return status, response, comments
+}
+
+func (s *Server) handleGroups(req *request) (int, interface{}) {
+	// Only /groups/ID/comments is supported
+	id, ok := req.id(1)
+	if !ok || len(req.path) < 3 || req.path[2] != "comments" {
+		return notFound()
+	}
+	if _, ok := s.jobGroups[int(id)]; !ok {
+		return failure(http.StatusNotFound, "Group %d does not exist", id)
+	}
+	status, response, updated := s.handleComments(req, s.groupComments[int(id)], 3)
+	s.groupComments[int(id)] = updated
+	return status, response
+}
+
+/* Job groups */
+
+// applyGroupParams applies the given parameters to the job group
+func applyGroupParams(group gopenqa.JobGroup, req *request) gopenqa.JobGroup {
+	if _, ok := req.params["name"]; ok {
+		group.Name = req.param("name")
+	}
+	if _, ok := req.params["description"]; ok {
+		group.Description = req.param("description")
+	}
+	if _, ok := req.params["template"]; ok {
+		group.Template = req.param("template")
+	}
+	ints := map[string]*int{
+		"parent_id":          &group.ParentID,
+		"build_version_sort": &group.BuildVersionSort,
+		"carry_over_bugrefs": &group.CarryOverBugrefs,
+		"default_priority":   &group.DefaultPriority,
+		"sort_order":         &group.SortOrder,
+	}
+	for name, value := range ints {
+		if _, ok := req.params[name]; ok {
+			*value = req.intParam(name)
+		}
+	}
+	return group
+}
+
+func sortedGroups(groups map[int]gopenqa.JobGroup) []gopenqa.JobGroup {
+	ret := make([]gopenqa.JobGroup, 0)
+	for _, group := range groups {
+		ret = append(ret, group)
+	}
+	sort.Slice(ret, func(i, j int) bool { return ret[i].ID < ret[j].ID })
+	return ret
+}
+
+func (s *Server) handleJobGroups(req *request) (int, interface{}) {
+	return s.handleGroupEntities(req, s.jobGroups, "job_group")
+}
+
+func (s *Server) handleParentGroups(req *request) (int, interface{}) {
+	if id, ok := req.id(1); ok && len(req.path) >= 3 && req.path[2] == "comments" {
+		if _, ok := s.parentGroups[int(id)]; !ok {
+			return failure(http.StatusNotFound, "Group %d does not exist", id)
+		}
+		status, response, updated := s.handleComments(req, s.parentComments[int(id)], 3)
+		s.parentComments[int(id)] = updated
+		return status, response
+	}
+	return s.handleGroupEntities(req, s.parentGroups, "parent_group")
+}
+
+func (s *Server) handleGroupEntities(req *request, groups map[int]gopenqa.JobGroup, entity string) (int, interface{}) {
+	if len(req.path) == 1 {
+		switch req.method {
+		case http.MethodGet:
+			return http.StatusOK, sortedGroups(groups)
+		case http.MethodPost:
+			if req.param("name") == "" {
+				return failure(http.StatusBadRequest, "Missing parameter 'name'")
+			}
+			for _, group := range groups {
+				if group.Name == req.param("name") {
+					return failure(http.StatusInternalServerError, "Group %s already exists", group.Name)
+				}
+			}
+			group := applyGroupParams(gopenqa.JobGroup{}, req)
+			group = s.addGroup(groups, entity, group)
+			return http.StatusOK, map[string]interface{}{"id": group.ID}
+		}
+		return methodNotAllowed(req)
+	}
+	id, ok := req.id(1)
+	if !ok {
+		return notFound()
+	}
+	group, ok := groups[int(id)]
+	if !ok {
+		return failure(http.StatusNotFound, "Job group %d does not exist", id)
+	}
+	if len(req.path) == 3 && req.path[2] == "jobs" && req.method == http.MethodGet && entity == "job_group" {
+		ids := make([]int64, 0)
+		for _, job := range s.sortedJobs() {
+			if job.GroupID == group.ID {
+				ids = append(ids, job.ID)
+			}
+		}
+		return http.StatusOK, map[string]interface{}{"ids": ids}
+	}
+	if len(req.path) != 2 {
+		return notFound()
+	}
+	switch req.method {
+	case http.MethodGet:
+		// openQA returns a list, also for a single group
+		return http.StatusOK, []gopenqa.JobGroup{group}
+	case http.MethodPut:
+		groups[group.ID] = applyGroupParams(group, req)
+		return http.StatusOK, map[string]interface{}{"id": group.ID}
+	case http.MethodDelete:
+		for _, job := range s.jobs {
+			if entity == "job_group" && job.GroupID == group.ID {
+				return failure(http.StatusBadRequest, "Job group %d is not empty", group.ID)
+			}
+		}
+		delete(groups, group.ID)
+		return http.StatusOK, map[string]interface{}{"id": group.ID}
+	}
+	return methodNotAllowed(req)
+}
+
+/* Machines, products and test suites */
+
+func (s *Server) encodeMachine(machine gopenqa.Machine) map[string]interface{} {
+	return map[string]interface{}{"id": machine.ID, "name": machine.Name, "backend": machine.Backend, "settings": encodeSettings(machine.Settings)}
+}
+
+func (s *Server) handleMachines(req *request) (int, interface{}) {
+	if len(req.path) == 1 {
+		switch req.method {
+		case http.MethodGet:
+			machines := make([]map[string]interface{}, 0)
+			for _, id := range sortedKeys(s.machines) {
+				machines = append(machines, s.encodeMachine(s.machines[id]))
+			}
+			return http.StatusOK, map[string]interface{}{"Machines": machines}
+		case http.MethodPost:
+			if req.param("name") == "" || req.param("backend") == "" {
+				return failure(http.StatusBadRequest, "Missing parameter 'name' or 'backend'")
+			}
+			machine := gopenqa.Machine{ID: int(s.nextID("machine")), Name: req.param("name"), Backend: req.param("backend"), Settings: req.settings()}
+			s.machines[machine.ID] = machine
+			return http.StatusOK, map[string]interface{}{"id": machine.ID}
+		}
+		return methodNotAllowed(req)
+	}
+	id, _ := req.id(1)
+	machine, ok := s.machines[int(id)]
+	if !ok || len(req.path) != 2 {
+		return failure(http.StatusNotFound, "Machine %d does not exist", id)
+	}
+	switch req.method {
+	case http.MethodGet:
+		return http.StatusOK, map[string]interface{}{"Machines": []map[string]interface{}{s.encodeMachine(machine)}}
+	case http.MethodPut:
+		if name := req.param("name"); name != "" {
+			machine.Name = name
+		}
+		if backend := req.param("backend"); backend != "" {
+			machine.Backend = backend
+		}
+		machine.Settings = req.settings()
+		s.machines[machine.ID] = machine
+		return http.StatusOK, map[string]interface{}{"id": machine.ID}
+	case http.MethodDelete:
+		delete(s.machines, machine.ID)
+		return http.StatusOK, map[string]interface{}{"result": 1}
+	}
+	return methodNotAllowed(req)
+}
+
+func (s *Server) encodeProduct(product gopenqa.Product) map[string]interface{} {
+	return map[string]interface{}{"id": product.ID, "arch": product.Arch, "distri": product.Distri, "flavor": product.Flavor, "version": product.Version, "group": product.Group, "settings": encodeSettings(product.Settings)}
+}
+
+func (s *Server) handleProducts(req *request) (int, interface{}) {
+	if len(req.path) == 1 {
+		switch req.method {
+		case http.MethodGet:
+			products := make([]map[string]interface{}, 0)
+			for _, id := range sortedKeys(s.products) {
+				products = append(products, s.encodeProduct(s.products[id]))
+			}
+			return http.StatusOK, map[string]interface{}{"Products": products}
+		case http.MethodPost:
+			if req.param("arch") == "" || req.param("distri") == "" || req.param("flavor") == "" || req.param("version") == "" {
+				return failure(http.StatusBadRequest, "Missing parameter 'arch', 'distri', 'flavor' or 'version'")
+			}
+			product := gopenqa.Product{ID: int(s.nextID("product")), Arch: req.param("arch"), Distri: req.param("distri"), Flavor: req.param("flavor"), Version: req.param("version"), Settings: req.settings()}
+			s.products[product.ID] = product
+			return http.StatusOK, map[string]interface{}{"id": product.ID}
+		}
+		return methodNotAllowed(req)
+	}
+	id, _ := req.id(1)
+	product, ok := s.products[int(id)]
+	if !ok || len(req.path) != 2 {
+		return failure(http.StatusNotFound, "Product %d does not exist", id)
+	}
+	switch req.method {
+	case http.MethodGet:
+		return http.StatusOK, map[string]interface{}{"Products": []map[string]interface{}{s.encodeProduct(product)}}
+	case http.MethodPut:
+		for name, value := range map[string]*string{"arch": &product.Arch, "distri": &product.Distri, "flavor": &product.Flavor, "version": &product.Version} {
+			if v := req.param(name); v != "" {
+				*value = v
+			}
+		}
+		product.Settings = req.settings()
+		s.products[product.ID] = product
+		return http.StatusOK, map[string]interface{}{"id": product.ID}
+	case http.MethodDelete:
+		delete(s.products, product.ID)
+		return http.StatusOK, map[string]interface{}{"result": 1}
+	}
+	return methodNotAllowed(req)
+}
+
+func (s *Server) encodeTestSuite(suite gopenqa.TestSuite) map[string]interface{} {
+	return map[string]interface{}{"id": suite.ID, "name": suite.Name, "description": suite.Description, "settings": encodeSettings(suite.Settings)}
+}
+
+func (s *Server) handleTestSuites(req *request) (int, interface{}) {
+	if len(req.path) == 1 {
+		switch req.method {
+		case http.MethodGet:
+			suites := make([]map[string]interface{}, 0)
+			for _, id := range sortedKeys(s.testSuites) {
+				suites = append(suites, s.encodeTestSuite(s.testSuites[id]))
+			}
+			return http.StatusOK, map[string]interface{}{"TestSuites": suites}
+		case http.MethodPost:
+			if req.param("name") == "" {
+				return failure(http.StatusBadRequest, "Missing parameter 'name'")
+			}
+			suite := gopenqa.TestSuite{ID: int(s.nextID("test_suite")), Name: req.param("name"), Description: req.param("description"), Settings: req.settings()}
+			s.testSuites[suite.ID] = suite
+			return http.StatusOK, map[string]interface{}{"id": suite.ID}
+		}
+		return methodNotAllowed(req)
+	}
+	id, _ := req.id(1)
+	suite, ok := s.testSuites[int(id)]
+	if !ok || len(req.path) != 2 {
+		return failure(http.StatusNotFound, "Test suite %d does not exist", id)
+	}
+	switch req.method {
+	case http.MethodGet:
+		return http.StatusOK, map[string]interface{}{"TestSuites": []map[string]interface{}{s.encodeTestSuite(suite)}}
+	case http.MethodPut:
+		if name := req.param("name"); name != "" {
+			suite.Name = name
+		}
+		if _, ok := req.params["description"]; ok {
+			suite.Description = req.param("description")
+		}
+		suite.Settings = req.settings()
+		s.testSuites[suite.ID] = suite
+		return http.StatusOK, map[string]interface{}{"id": suite.ID}
+	case http.MethodDelete:
+		delete(s.testSuites, suite.ID)
+		return http.StatusOK, map[string]interface{}{"result": 1}
+	}
+	return methodNotAllowed(req)
+}
+
+func sortedKeys[T any](entities map[int]T) []int {
+	keys := make([]int, 0)
+	for k := range entities {
+		keys = append(keys, k)
+	}
+	sort.Ints(keys)
+	return keys
+}
+
+/* Job templates and scheduling */
+
+func (s *Server) handleJobTemplates(req *request) (int, interface{}) {
+	if len(req.path) == 1 {
+		switch req.method {
+		case http.MethodGet:
+			templates := make([]gopenqa.JobTemplate, 0)
+			for _, id := range sortedKeys(s.jobTemplates) {
+				templates = append(templates, s.jobTemplates[id])
+			}
+			return http.StatusOK, map[string]interface{}{"JobTemplates": templates}
+		case http.MethodPost:
+			group, ok := s.jobGroups[req.intParam("group_id")]
+			machine, ok2 := s.machines[req.intParam("machine_id")]
+			product, ok3 := s.products[req.intParam("product_id")]
+			suite, ok4 := s.testSuites[req.intParam("test_suite_id")]
+			if !ok || !ok2 || !ok3 || !ok4 {
+				return failure(http.StatusBadRequest, "Invalid group, machine, product or test suite")
+			}
+			template := gopenqa.JobTemplate{ID: int(s.nextID("job_template")), GroupName: group.Name, Machine: machine, Product: product, TestSuite: suite, Priority: req.intParam("prio")}
+			s.jobTemplates[template.ID] = template
+			return http.StatusOK, map[string]interface{}{"id": template.ID}
+		}
+		return methodNotAllowed(req)
+	}
+	id, _ := req.id(1)
+	template, ok := s.jobTemplates[int(id)]
+	if !ok || len(req.path) != 2 {
+		return failure(http.StatusNotFound, "Job template %d does not exist", id)
+	}
+	switch req.method {
+	case http.MethodGet:
+		return http.StatusOK, map[string]interface{}{"JobTemplates": []gopenqa.JobTemplate{template}}
+	case http.MethodDelete:
+		delete(s.jobTemplates, template.ID)
+		return http.StatusOK, map[string]interface{}{"result": 1}
+	}
+	return methodNotAllowed(req)
+}
+
+func (s *Server) handleScheduling(req *request) (int, interface{}) {
+	id, ok := req.id(1)
+	if !ok {
+		return notFound()
+	}
+	if _, ok := s.jobGroups[int(id)]; !ok {
+		return failure(http.StatusNotFound, "Job group %d does not exist", id)
+	}
+	switch req.method {
+	case http.MethodGet:
+		return http.StatusOK, rawResponse(s.schedulingYAML[int(id)])
+	case http.MethodPost:
+		// openQA expects the YAML as "template" parameter, but the YAML might also be sent as raw body
+		yaml := req.param("template")
+		if yaml == "" {
+			yaml = string(req.body)
+		}
+		s.schedulingYAML[int(id)] = yaml
+		return http.StatusOK, map[string]interface{}{"id": id}
+	}
+	return methodNotAllowed(req)
+}
+
+// handleIsos schedules a product, i.e. creates jobs for all job templates matching the given DISTRI, VERSION, FLAVOR and ARCH
+func (s *Server) handleIsos(req *request) (int, interface{}) {
+	if len(req.path) != 1 || req.method != http.MethodPost {
+		return notFound()
+	}
+	for _, key := range []string{"DISTRI", "VERSION", "FLAVOR", "ARCH"} {
+		if req.param(key) == "" {
+			return failure(http.StatusBadRequest, "Missing parameter '%s'", key)
+		}
+	}
+	ids := make([]int64, 0)
+	for _, tid := range sortedKeys(s.jobTemplates) {
+		template := s.jobTemplates[tid]
+		product := template.Product
+		if product.Distri != req.param("DISTRI") || product.Version != req.param("VERSION") || product.Flavor != req.param("FLAVOR") || product.Arch != req.param("ARCH") {
+			continue
+		}
+		params := url.Values{}
+		for k, v := range req.params {
+			params[k] = v
+		}
+		params.Set("TEST", template.TestSuite.Name)
+		params.Set("MACHINE", template.Machine.Name)
+		params.Set("BACKEND", template.Machine.Backend)
+		params.Set("_GROUP", template.GroupName)
+		params.Set("_PRIORITY", fmt.Sprintf("%d", template.Priority))
+		job := s.createJob(params)
+		ids = append(ids, job.ID)
+	}
+	scheduledProduct := s.nextID("scheduled_product")
+	return http.StatusOK, map[string]interface{}{"count": len(ids), "ids": ids, "failed": []string{}, "scheduled_product_id": scheduledProduct}
+}
+
+/* Workers */
+
+func (s *Server) handleWorkers(req *request) (int, interface{}) {
+	if len(req.path) == 1 {
+		if req.method != http.MethodGet {
+			return methodNotAllowed(req)
+		}
+		workers := make([]gopenqa.Worker, 0)
+		for _, id := range sortedKeys(s.workers) {
+			workers = append(workers, s.workers[id])
+		}
+		return http.StatusOK, map[string]interface{}{"workers": workers}
+	}
+	id, _ := req.id(1)
+	worker, ok := s.workers[int(id)]
+	if !ok {
+		return failure(http.StatusNotFound, "Worker %d does not exist", id)
+	}
+	if len(req.path) == 3 && req.path[2] == "commands" && req.method == http.MethodPost {
+		command := req.param("command")
+		if !gopenqa.IsWorkerCommand(command) {
+			return failure(http.StatusBadRequest, "Invalid command %s", command)
+		}
+		s.workerCommands[worker.ID] = append(s.workerCommands[worker.ID], command)
+		return http.StatusOK, map[string]interface{}{}
+	}
+	if len(req.path) != 2 {
+		return notFound()
+	}
+	switch req.method {
+	case http.MethodGet:
+		return http.StatusOK, map[string]interface{}{"worker": worker}
+	case http.MethodDelete:
+		if !worker.IsOffline() {
+			return failure(http.StatusBadRequest, "Worker %s:%d status is not offline", worker.Host, worker.Instance)
+		}
+		delete(s.workers, worker.ID)
+		return http.StatusOK, map[string]interface{}{"message": fmt.Sprintf("Delete worker %s:%d successfully.", worker.Host, worker.Instance)}
+	}
+	return methodNotAllowed(req)
+}
@@ -0,0 +1,385 @@
+/*
+ * Package gopenqatest provides an in-memory fake openQA instance for testing tools built on gopenqa.
+ * The fake serves the openQA API for jobs, job groups, machines, products, test suites, comments, workers and scheduling
+ * and verifies the API key and hash of all modifying requests, like openQA does.
+ * Broker is an in-memory AMQP broker for testing RabbitMQ consumers without a live RabbitMQ server.
+ */
+package gopenqatest
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/os-autoinst/gopenqa"
+)
+
+// Credentials of the API key, which is registered on every new server
+const DefaultUser = "admin"
+const DefaultAPIKey = "1234567890ABCDEF"
+const DefaultAPISecret = "FEDCBA0987654321"
+
+// Maximum allowed difference between the X-API-Microtime of a request and the server time
+const maxTimestampSkew = 300
+
+const timeFormat = "2006-01-02 15:04:05 -0700"
+
+type apiKey struct {
+	user   string
+	secret string
+}
+
+/* Server is a fake openQA instance. All entities are kept in memory */
+type Server struct {
+	URL            string // Base URL of the server, e.g. http://127.0.0.1:12345
+	server         *httptest.Server
+	mutex          sync.Mutex
+	keys           map[string]apiKey
+	ids            map[string]int64 // Last assigned ID per entity type
+	jobs           map[int64]gopenqa.Job
+	jobGroups      map[int]gopenqa.JobGroup
+	parentGroups   map[int]gopenqa.JobGroup
+	machines       map[int]gopenqa.Machine
+	products       map[int]gopenqa.Product
+	testSuites     map[int]gopenqa.TestSuite
+	jobTemplates   map[int]gopenqa.JobTemplate
+	schedulingYAML map[int]string              // Job templates scheduling YAML per job group
+	jobComments    map[int64][]gopenqa.Comment // Comments per job
+	groupComments  map[int][]gopenqa.Comment   // Comments per job group
+	parentComments map[int][]gopenqa.Comment   // Comments per parent job group
+	workers        map[int]gopenqa.Worker
+	workerCommands map[int][]string
+	requests       []string // Log of all requests as "METHOD PATH"
+}
+
+/* NewServer starts a new empty fake openQA instance. The server must be closed via Close after usage */
+func NewServer() *Server {
+	s := &Server{}
+	s.Reset()
+	s.server = httptest.NewServer(http.HandlerFunc(s.serve))
+	s.URL = s.server.URL
+	return s
+}
+
+/* Close shuts the server down */
+func (s *Server) Close() {
+	s.server.Close()
+}
+
+/* Reset removes all entities and registered API keys, except for the default API key */
+func (s *Server) Reset() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.keys = make(map[string]apiKey, 0)
+	s.keys[DefaultAPIKey] = apiKey{user: DefaultUser, secret: DefaultAPISecret}
+	s.ids = make(map[string]int64, 0)
+	s.jobs = make(map[int64]gopenqa.Job, 0)
+	s.jobGroups = make(map[int]gopenqa.JobGroup, 0)
+	s.parentGroups = make(map[int]gopenqa.JobGroup, 0)
+	s.machines = make(map[int]gopenqa.Machine, 0)
+	s.products = make(map[int]gopenqa.Product, 0)
+	s.testSuites = make(map[int]gopenqa.TestSuite, 0)
+	s.jobTemplates = make(map[int]gopenqa.JobTemplate, 0)
+	s.schedulingYAML = make(map[int]string, 0)
+	s.jobComments = make(map[int64][]gopenqa.Comment, 0)
+	s.groupComments = make(map[int][]gopenqa.Comment, 0)
+	s.parentComments = make(map[int][]gopenqa.Comment, 0)
+	s.workers = make(map[int]gopenqa.Worker, 0)
+	s.workerCommands = make(map[int][]string, 0)
+	s.requests = make([]string, 0)
+}
+
+/* Instance returns a gopenqa instance for this server, which uses the default API key */
+func (s *Server) Instance() *gopenqa.Instance {
+	instance := gopenqa.CreateInstance(s.URL)
+	instance.SetApiKey(DefaultAPIKey, DefaultAPISecret)
+	return &instance
+}
+
+/* AddAPIKey registers an additional API key for the given user */
+func (s *Server) AddAPIKey(user string, key string, secret string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.keys[key] = apiKey{user: user, secret: secret}
+}
+
+/* Requests returns all requests received so far as "METHOD PATH" */
+func (s *Server) Requests() []string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	ret := make([]string, len(s.requests))
+	copy(ret, s.requests)
+	return ret
+}
+
+// nextID returns the next free ID of the given entity type. Must be called with the mutex held
+func (s *Server) nextID(entity string) int64 {
+	s.ids[entity]++
+	return s.ids[entity]
+}
+
+// useID ensures that the given ID won't be assigned to another entity of the same type. Must be called with the mutex held
+func (s *Server) useID(entity string, id int64) {
+	if id > s.ids[entity] {
+		s.ids[entity] = id
+	}
+}
+
+/* AddJob adds the given job. If the ID is 0, a new ID is assigned. Returns the stored job */
+func (s *Server) AddJob(job gopenqa.Job) gopenqa.Job {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.addJob(job)
+}
+
+func (s *Server) addJob(job gopenqa.Job) gopenqa.Job {
+	if job.ID == 0 {
+		job.ID = s.nextID("job")
+	} else {
+		s.useID("job", job.ID)
+	}
+	if job.State == "" {
+		job.State = "scheduled"
+	}
+	if job.Name == "" {
+		job.Name = job.Test
+	}
+	job.Link = fmt.Sprintf("%s/tests/%d", s.URL, job.ID)
+	s.jobs[job.ID] = job
+	return job
+}
+
+/* Job returns the job with the given ID */
+func (s *Server) Job(id int64) (gopenqa.Job, bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	job, ok := s.jobs[id]
+	return job, ok
+}
+
+/* Jobs returns all jobs ordered by their ID */
+func (s *Server) Jobs() []gopenqa.Job {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.sortedJobs()
+}
+
+func (s *Server) sortedJobs() []gopenqa.Job {
+	jobs := make([]gopenqa.Job, 0)
+	for _, job := range s.jobs {
+		jobs = append(jobs, job)
+	}
+	sort.Slice(jobs, func(i, j int) bool { return jobs[i].ID < jobs[j].ID })
+	return jobs
+}
+
+/* SetJobState sets the state and result of the given job, e.g. to simulate a finished job */
+func (s *Server) SetJobState(id int64, state string, result string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	job, ok := s.jobs[id]
+	if !ok {
+		return fmt.Errorf("job %d not found", id)
+	}
+	job.State = state
+	job.Result = result
+	now := time.Now().UTC().Format("2006-01-02T15:04:05")
+	if state == "running" && job.Tstarted == "" {
+		job.Tstarted = now
+	}
+	if state == "done" || state == "cancelled" {
+		job.Tfinished = now
+	}
+	s.jobs[id] = job
+	return nil
+}
+
+/* AddJobGroup adds the given job group. If the ID is 0, a new ID is assigned */
+func (s *Server) AddJobGroup(group gopenqa.JobGroup) gopenqa.JobGroup {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.addGroup(s.jobGroups, "job_group", group)
+}
+
+/* AddParentJobGroup adds the given parent job group. If the ID is 0, a new ID is assigned */
+func (s *Server) AddParentJobGroup(group gopenqa.JobGroup) gopenqa.JobGroup {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.addGroup(s.parentGroups, "parent_group", group)
+}
+
+func (s *Server) addGroup(groups map[int]gopenqa.JobGroup, entity string, group gopenqa.JobGroup) gopenqa.JobGroup {
+	if group.ID == 0 {
+		group.ID = int(s.nextID(entity))
+	} else {
+		s.useID(entity, int64(group.ID))
+	}
+	groups[group.ID] = group
+	return group
+}
+
+/* AddMachine adds the given machine. If the ID is 0, a new ID is assigned */
+func (s *Server) AddMachine(machine gopenqa.Machine) gopenqa.Machine {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if machine.ID == 0 {
+		machine.ID = int(s.nextID("machine"))
+	} else {
+		s.useID("machine", int64(machine.ID))
+	}
+	s.machines[machine.ID] = machine
+	return machine
+}
+
+/* AddProduct adds the given product. If the ID is 0, a new ID is assigned */
+func (s *Server) AddProduct(product gopenqa.Product) gopenqa.Product {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if product.ID == 0 {
+		product.ID = int(s.nextID("product"))
+	} else {
+		s.useID("product", int64(product.ID))
+	}
+	s.products[product.ID] = product
+	return product
+}
+
+/* AddTestSuite adds the given test suite. If the ID is 0, a new ID is assigned */
+func (s *Server) AddTestSuite(suite gopenqa.TestSuite) gopenqa.TestSuite {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if suite.ID == 0 {
+		suite.ID = int(s.nextID("test_suite"))
+	} else {
+		s.useID("test_suite", int64(suite.ID))
+	}
+	s.testSuites[suite.ID] = suite
+	return suite
+}
+
+/* AddJobTemplate adds the given job template, which is used when scheduling products. If the ID is 0, a new ID is assigned */
+func (s *Server) AddJobTemplate(template gopenqa.JobTemplate) gopenqa.JobTemplate {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if template.ID == 0 {
+		template.ID = int(s.nextID("job_template"))
+	} else {
+		s.useID("job_template", int64(template.ID))
+	}
+	s.jobTemplates[template.ID] = template
+	return template
+}
+
+/* AddComment adds a comment to the given job. If the ID is 0, a new ID is assigned */
+func (s *Server) AddComment(job int64, comment gopenqa.Comment) gopenqa.Comment {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	comment = s.newComment(comment)
+	s.jobComments[job] = append(s.jobComments[job], comment)
+	return comment
+}
+
+/* AddJobGroupComment adds a comment to the given job group. If the ID is 0, a new ID is assigned */
+func (s *Server) AddJobGroupComment(group int, comment gopenqa.Comment) gopenqa.Comment {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	comment = s.newComment(comment)
+	s.groupComments[group] = append(s.groupComments[group], comment)
+	return comment
+}
+
+func (s *Server) newComment(comment gopenqa.Comment) gopenqa.Comment {
+	if comment.ID == 0 {
+		comment.ID = int(s.nextID("comment"))
+	} else {
+		s.useID("comment", int64(comment.ID))
+	}
+	if comment.Created == "" {
+		comment.Created = time.Now().UTC().Format(timeFormat)
+	}
+	if comment.Updated == "" {
+		comment.Updated = comment.Created
+	}
+	if comment.User == "" {
+		comment.User = DefaultUser
+	}
+	comment.BugRefs = bugrefs(comment.Text)
+	return comment
+}
+
+/* AddWorker adds the given worker. If the ID is 0, a new ID is assigned */
+func (s *Server) AddWorker(worker gopenqa.Worker) gopenqa.Worker {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if worker.ID == 0 {
+		worker.ID = int(s.nextID("worker"))
+	} else {
+		s.useID("worker", int64(worker.ID))
+	}
+	if worker.Status == "" {
+		worker.Status = "idle"
+	}
+	s.workers[worker.ID] = worker
+	return worker
+}
+
+/* Worker returns the worker with the given ID */
+func (s *Server) Worker(id int) (gopenqa.Worker, bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	worker, ok := s.workers[id]
+	return worker, ok
+}
+
+/* WorkerCommands returns all commands sent to the given worker */
+func (s *Server) WorkerCommands(id int) []string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	ret := make([]string, len(s.workerCommands[id]))
+	copy(ret, s.workerCommands[id])
+	return ret
+}
+
+/* SchedulingYAML returns the job templates scheduling YAML of the given job group */
+func (s *Server) SchedulingYAML(group int) string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.schedulingYAML[group]
+}
+
+// authenticate checks the API key and hash of the given request like openQA does. Returns the user name
+func (s *Server) authenticate(r *http.Request) (string, error) {
+	key := r.Header.Get("X-API-Key")
+	hash := r.Header.Get("X-API-Hash")
+	microtime := r.Header.Get("X-API-Microtime")
+	if key == "" || hash == "" || microtime == "" {
+		return "", fmt.Errorf("no api key")
+	}
+	s.mutex.Lock()
+	credentials, ok := s.keys[key]
+	s.mutex.Unlock()
+	if !ok {
+		return "", fmt.Errorf("api key not found")
+	}
+	timestamp, err := strconv.ParseInt(microtime, 10, 64)
+	if err != nil {
+		return "", fmt.Errorf("invalid timestamp")
+	}
+	if skew := time.Now().Unix() - timestamp; skew > maxTimestampSkew || skew < -maxTimestampSkew {
+		return "", fmt.Errorf("timestamp mismatch")
+	}
+	// The hash is the hmac_sha1 of the request path (including the query) and the timestamp
+	h := hmac.New(sha1.New, []byte(credentials.secret))
+	h.Write([]byte(fmt.Sprintf("%s%d", r.RequestURI, timestamp)))
+	expected := fmt.Sprintf("%x", h.Sum(nil))
+	if !hmac.Equal([]byte(expected), []byte(hash)) {
+		return "", fmt.Errorf("hash mismatch")
+	}
+	return credentials.user, nil
+}
@@ -0,0 +1,780 @@
+package gopenqatest
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/os-autoinst/gopenqa"
+	"gotest.tools/assert"
+)
+
+func TestJobs(t *testing.T) {
+	server := NewServer()
+	defer server.Close()
+	instance := server.Instance()
+
+	job := server.AddJob(gopenqa.Job{Test: "minimal", Settings: gopenqa.Settings{Arch: "x86_64", Machine: "64bit"}, GroupID: 1})
+	server.AddJob(gopenqa.Job{Test: "textmode", State: "done", Result: "passed"})
+
+	fetched, err := instance.GetJob(job.ID)
+	assert.NilError(t, err)
+	assert.Equal(t, fetched.Test, "minimal")
+	assert.Equal(t, fetched.State, "scheduled")
+	assert.Equal(t, fetched.Link, server.URL+"/tests/1")
+	_, err = instance.GetJob(42)
+	assert.Assert(t, err != nil, "fetching a non-existing job should fail")
+
+	jobs, err := instance.GetJobs([]int64{1, 2})
+	assert.NilError(t, err)
+	assert.Equal(t, len(jobs), 2)
+	state, err := instance.GetJobState(2)
+	assert.NilError(t, err)
+	assert.Equal(t, state.State, "done")
+	assert.Equal(t, state.Result, "passed")
+
+	// Cloned jobs are followed
+	clone := server.AddJob(gopenqa.Job{Test: "minimal"})
+	job.CloneID = clone.ID
+	server.AddJob(job)
+	followed, err := instance.GetJobsFollow([]int64{job.ID})
+	assert.NilError(t, err)
+	assert.Equal(t, len(followed), 1)
+	assert.Equal(t, followed[0].ID, clone.ID)
+
+	assert.NilError(t, instance.DeleteJob(2))
+	assert.Equal(t, len(server.Jobs()), 2)
+}
+
+func TestGetJobResponses(t *testing.T) {
+	server := NewServer()
+	defer server.Close()
+	instance := server.Instance()
+	instance.SetAllowParallel(true)
+
+	ids := make([]int64, 0)
+	for n := 0; n < 1000; n++ {
+		job := server.AddJob(gopenqa.Job{Test: "minimal", State: "done", Result: "passed"})
+		ids = append([]int64{job.ID}, ids...)
+	}
+	// Restart some jobs
+	for _, id := range []int64{10, 500, 990} {
+		job := server.Jobs()[id-1]
+		clone := server.AddJob(gopenqa.Job{Test: "minimal", State: "running"})
+		job.CloneID = clone.ID
+		server.AddJob(job)
+	}
+	ids = append(ids, 4242, 10)
+
+	// Many IDs are fetched in multiple requests and returned in the given order
+	jobs, err := instance.GetJobs(ids)
+	assert.NilError(t, err)
+	assert.Equal(t, len(jobs), 1001)
+	assert.Equal(t, jobs[0].ID, int64(1000))
+	assert.Equal(t, jobs[1000].ID, int64(10))
+	assert.Assert(t, len(server.Requests()) > 1)
+
+	responses := instance.GetJobResponses(ids, true)
+	assert.Equal(t, len(responses), 1002)
+	failed := 0
+	for n, response := range responses {
+		assert.Equal(t, response.ID, ids[n])
+		if response.Err != nil {
+			failed++
+			assert.Equal(t, response.ID, int64(4242))
+		} else if response.ID == 10 || response.ID == 500 || response.ID == 990 {
+			assert.Equal(t, response.Job.State, "running")
+			assert.Assert(t, response.Job.ID > 1000)
+		} else {
+			assert.Equal(t, response.Job.ID, response.ID)
+		}
+	}
+	assert.Equal(t, failed, 1)
+
+	jobs, err = instance.GetJobsFollow([]int64{500, 501})
+	assert.NilError(t, err)
+	assert.Equal(t, len(jobs), 2)
+	assert.Equal(t, jobs[0].State, "running")
+	assert.Equal(t, jobs[1].ID, int64(501))
+}
+
+func TestSearchJobs(t *testing.T) {
+	server := NewServer()
+	defer server.Close()
+	instance := server.Instance()
+
+	tw := gopenqa.Settings{Arch: "x86_64", Machine: "64bit", Build: "20240101", Distri: "opensuse", Version: "Tumbleweed", Flavor: "DVD"}
+	aarch64 := tw
+	aarch64.Arch = "aarch64"
+	failed := server.AddJob(gopenqa.Job{Test: "minimal", GroupID: 1, State: "done", Result: "failed", Settings: tw})
+	server.AddJob(gopenqa.Job{Test: "minimal", GroupID: 1, State: "done", Result: "passed", Settings: aarch64})
+	server.AddJob(gopenqa.Job{Test: "textmode", GroupID: 2, State: "running", Settings: tw})
+
+	jobs, err := instance.SearchJobs(gopenqa.JobQuery{Distri: "opensuse", Arch: "x86_64"})
+	assert.NilError(t, err)
+	assert.Equal(t, len(jobs), 2)
+	jobs, err = instance.SearchJobs(gopenqa.JobQuery{Build: "20240101", GroupID: 1, Result: []string{"failed", "passed"}})
+	assert.NilError(t, err)
+	assert.Equal(t, len(jobs), 2)
+	jobs, err = instance.SearchJobs(gopenqa.JobQuery{State: []string{"running"}})
+	assert.NilError(t, err)
+	assert.Equal(t, len(jobs), 1)
+	assert.Equal(t, jobs[0].Test, "textmode")
+	assert.Equal(t, jobs[0].Link, server.URL+"/tests/3")
+
+	// Restart the failed job. Without following clones both jobs are returned
+	clone := server.AddJob(gopenqa.Job{Test: "minimal", GroupID: 1, State: "running", Settings: tw})
+	failed.CloneID = clone.ID
+	server.AddJob(failed)
+	jobs, err = instance.SearchJobs(gopenqa.JobQuery{Test: "minimal", Arch: "x86_64"})
+	assert.NilError(t, err)
+	assert.Equal(t, len(jobs), 2)
+	jobs, err = instance.SearchJobs(gopenqa.JobQuery{Test: "minimal", Arch: "x86_64", FollowClones: true})
+	assert.NilError(t, err)
+	assert.Equal(t, len(jobs), 1)
+	assert.Equal(t, jobs[0].ID, clone.ID)
+	jobs, err = instance.SearchJobs(gopenqa.JobQuery{Result: []string{"failed"}, FollowClones: true})
+	assert.NilError(t, err)
+	assert.Equal(t, len(jobs), 1)
+	assert.Equal(t, jobs[0].State, "running")
+
+	jobs, err = instance.SearchJobs(gopenqa.JobQuery{Test: "minimal", Latest: true})
+	assert.NilError(t, err)
+	assert.Equal(t, len(jobs), 2)
+}
+
+func TestAuthentication(t *testing.T) {
+	server := NewServer()
+	defer server.Close()
+
+	instance := gopenqa.CreateInstance(server.URL)
+	_, err := instance.PostMachine(gopenqa.Machine{Name: "64bit", Backend: "qemu"})
+	assert.Assert(t, err != nil, "posting without API key should fail")
+	instance.SetApiKey(DefaultAPIKey, "WRONGSECRET")
+	_, err = instance.PostJobGroup(gopenqa.JobGroup{Name: "Test group"})
+	assert.Assert(t, err != nil, "posting with a wrong secret should fail")
+	groups, err := instance.GetJobGroups()
+	assert.NilError(t, err)
+	assert.Equal(t, len(groups), 0)
+
+	server.AddAPIKey("operator", "KEY", "SECRET")
+	instance.SetApiKey("KEY", "SECRET")
+	_, err = instance.PostJobGroup(gopenqa.JobGroup{Name: "Test group"})
+	assert.NilError(t, err)
+}
+
+func TestDo(t *testing.T) {
+	server := NewServer()
+	defer server.Close()
+	instance := gopenqa.CreateInstance(server.URL)
+
+	status, _, err := instance.Do("POST", "/api/v1/machines", "application/x-www-form-urlencoded", []byte("name=64bit&backend=qemu"))
+	assert.NilError(t, err)
+	assert.Equal(t, status, 403)
+
+	instance.SetApiKey(DefaultAPIKey, DefaultAPISecret)
+	status, _, err = instance.Do("POST", "api/v1/machines", "application/x-www-form-urlencoded", []byte("name=64bit&backend=qemu"))
+	assert.NilError(t, err)
+	assert.Equal(t, status, 200)
+	machines, err := instance.GetMachines()
+	assert.NilError(t, err)
+	assert.Equal(t, len(machines), 1)
+	assert.Equal(t, machines[0].Backend, "qemu")
+
+	// The query is part of the signed path
+	status, buf, err := instance.Do("GET", "/api/v1/machines?name=64bit", "", nil)
+	assert.NilError(t, err)
+	assert.Equal(t, status, 200)
+	assert.Assert(t, strings.Contains(string(buf), "64bit"))
+	status, _, err = instance.Do("DELETE", fmt.Sprintf("/api/v1/machines/%d?force=1", machines[0].ID), "", nil)
+	assert.NilError(t, err)
+	assert.Equal(t, status, 200)
+
+	// Status codes other than 200 are no errors
+	status, _, err = instance.Do("GET", "/api/v1/nonexisting", "", nil)
+	assert.NilError(t, err)
+	assert.Equal(t, status, 404)
+	status, _, err = instance.Do("PUT", "/api/v1/machines", "", nil)
+	assert.NilError(t, err)
+	assert.Equal(t, status, 405)
+}
+
+func TestConfiguration(t *testing.T) {
+	server := NewServer()
+	defer server.Close()
+	instance := server.Instance()
+
+	machine, err := instance.PostMachine(gopenqa.Machine{Name: "64bit", Backend: "qemu", Settings: map[string]string{"QEMUCPU": "host"}})
+	assert.NilError(t, err)
+	assert.Equal(t, machine.ID, 1)
+	machines, err := instance.GetMachines()
+	assert.NilError(t, err)
+	assert.Equal(t, len(machines), 1)
+	assert.Equal(t, machines[0].Name, "64bit")
+	assert.Equal(t, machines[0].Settings["QEMUCPU"], "host")
+
+	product, err := instance.PostProduct(gopenqa.Product{Arch: "x86_64", Distri: "opensuse", Flavor: "DVD", Version: "Tumbleweed"})
+	assert.NilError(t, err)
+	fetched, err := instance.GetProduct(product.ID)
+	assert.NilError(t, err)
+	assert.Equal(t, fetched.Distri, "opensuse")
+
+	group, err := instance.PostJobGroup(gopenqa.JobGroup{Name: "openSUSE Tumbleweed", DefaultPriority: 45})
+	assert.NilError(t, err)
+	fetchedGroup, err := instance.GetJobGroup(group.ID)
+	assert.NilError(t, err)
+	assert.Equal(t, fetchedGroup.Name, "openSUSE Tumbleweed")
+	assert.Equal(t, fetchedGroup.DefaultPriority, 45)
+
+	yaml := "defaults:\n  x86_64:\n    machine: 64bit\n"
+	assert.NilError(t, instance.PostJobTemplateYAML(group.ID, yaml))
+	assert.Equal(t, server.SchedulingYAML(group.ID), yaml)
+	fetchedYAML, err := instance.GetJobTemplateYAML(group.ID)
+	assert.NilError(t, err)
+	assert.Equal(t, fetchedYAML, yaml)
+
+	// Groups with jobs cannot be deleted
+	server.AddJob(gopenqa.Job{Test: "minimal", GroupID: group.ID})
+	assert.Assert(t, instance.DeleteJobGroup(group.ID) != nil, "deleting a non-empty job group should fail")
+	assert.NilError(t, instance.DeleteJobGroupJobs(group.ID))
+	assert.NilError(t, instance.DeleteJobGroup(group.ID))
+
+	assert.NilError(t, instance.DeleteMachine(machine.ID))
+	machines, err = instance.GetMachines()
+	assert.NilError(t, err)
+	assert.Equal(t, len(machines), 0)
+}
+
+func TestBulkDelete(t *testing.T) {
+	server := NewServer()
+	defer server.Close()
+	instance := server.Instance()
+
+	tw := server.AddJobGroup(gopenqa.JobGroup{Name: "openSUSE Tumbleweed"})
+	leap := server.AddJobGroup(gopenqa.JobGroup{Name: "openSUSE Leap"})
+	staging := server.AddJobGroup(gopenqa.JobGroup{Name: "Staging A"})
+	machine := server.AddMachine(gopenqa.Machine{Name: "64bit", Backend: "qemu"})
+	server.AddMachine(gopenqa.Machine{Name: "uefi", Backend: "qemu"})
+	server.AddJobTemplate(gopenqa.JobTemplate{GroupName: tw.Name, Machine: machine})
+	server.AddJob(gopenqa.Job{Test: "minimal", GroupID: tw.ID, State: "running"})
+	server.AddJob(gopenqa.Job{Test: "minimal", GroupID: leap.ID, State: "done", Tfinished: time.Now().Add(-48 * time.Hour).UTC().Format("2006-01-02T15:04:05")})
+
+	groups, err := instance.FilterJobGroups(gopenqa.DeleteFilter{Name: "^openSUSE"})
+	assert.NilError(t, err)
+	assert.Equal(t, len(groups), 2)
+	groups, err = instance.FilterJobGroups(gopenqa.DeleteFilter{MinID: int64(leap.ID)})
+	assert.NilError(t, err)
+	assert.Equal(t, len(groups), 2)
+	groups, err = instance.FilterJobGroups(gopenqa.DeleteFilter{Unused: true, MaxID: int64(leap.ID)})
+	assert.NilError(t, err)
+	assert.Equal(t, len(groups), 1)
+	assert.Equal(t, groups[0].ID, leap.ID)
+	// Job groups without jobs are old as well
+	groups, err = instance.FilterJobGroups(gopenqa.DeleteFilter{OlderThan: 24 * time.Hour})
+	assert.NilError(t, err)
+	assert.Equal(t, len(groups), 2)
+	assert.Equal(t, groups[1].ID, staging.ID)
+	_, err = instance.FilterJobGroups(gopenqa.DeleteFilter{Name: "("})
+	assert.Assert(t, err != nil, "invalid regular expression should fail")
+
+	// Dry runs don't delete anything
+	report := instance.DeleteJobGroups(groups, gopenqa.DeleteOptions{DryRun: true})
+	assert.Equal(t, len(report), 2)
+	assert.Equal(t, report.Deleted(), 0)
+	assert.Equal(t, len(server.Jobs()), 2)
+	progress := 0
+	report = instance.DeleteJobGroups(groups, gopenqa.DeleteOptions{Progress: func(gopenqa.DeleteOutcome) { progress++ }})
+	assert.NilError(t, report.Err())
+	assert.Equal(t, report.Deleted(), 2)
+	assert.Equal(t, progress, 2)
+	assert.Equal(t, len(server.Jobs()), 1)
+	groups, err = instance.GetJobGroups()
+	assert.NilError(t, err)
+	assert.Equal(t, len(groups), 1)
+	assert.Equal(t, groups[0].ID, tw.ID)
+
+	// Deleting the used job group removes its job templates as well
+	report = instance.DeleteJobGroups(groups, gopenqa.DeleteOptions{})
+	assert.NilError(t, report.Err())
+	templates, err := instance.GetJobTemplates()
+	assert.NilError(t, err)
+	assert.Equal(t, len(templates), 0)
+
+	// Failures abort the deletion, unless ContinueOnError is set
+	machines, err := instance.FilterMachines(gopenqa.DeleteFilter{Name: "uefi"})
+	assert.NilError(t, err)
+	assert.Equal(t, len(machines), 1)
+	_, err = instance.FilterMachines(gopenqa.DeleteFilter{OlderThan: time.Hour})
+	assert.Assert(t, err != nil, "age filter for machines should fail")
+	missing := gopenqa.Machine{ID: 42, Name: "missing"}
+	report = instance.DeleteMachines([]gopenqa.Machine{missing, machines[0]}, gopenqa.DeleteOptions{})
+	assert.Equal(t, report.Failed(), 1)
+	assert.Equal(t, report.Skipped(), 1)
+	assert.Assert(t, report.Err() != nil)
+	report = instance.DeleteMachines([]gopenqa.Machine{missing, machines[0]}, gopenqa.DeleteOptions{ContinueOnError: true})
+	assert.Equal(t, report.Failed(), 1)
+	assert.Equal(t, report.Deleted(), 1)
+	assert.Equal(t, report[1].Name, "uefi")
+	machines, err = instance.GetMachines()
+	assert.NilError(t, err)
+	assert.Equal(t, len(machines), 1)
+}
+
+func TestComments(t *testing.T) {
+	server := NewServer()
+	defer server.Close()
+	instance := server.Instance()
+
+	job := server.AddJob(gopenqa.Job{Test: "minimal"})
+	server.AddComment(job.ID, gopenqa.Comment{Text: "Known issue poo#42"})
+	comments, err := instance.GetComments(job.ID)
+	assert.NilError(t, err)
+	assert.Equal(t, len(comments), 1)
+	assert.Equal(t, comments[0].User, DefaultUser)
+	assert.DeepEqual(t, comments[0].BugRefs, []string{"poo#42"})
+}
+
+func TestWorkers(t *testing.T) {
+	server := NewServer()
+	defer server.Close()
+	instance := server.Instance()
+
+	server.AddWorker(gopenqa.Worker{Host: "openqaworker1", Instance: 1})
+	server.AddWorker(gopenqa.Worker{Host: "openqaworker1", Instance: 2, Status: "dead"})
+
+	workers, err := instance.GetWorkers()
+	assert.NilError(t, err)
+	assert.Equal(t, len(workers), 2)
+	worker, err := instance.GetWorker(2)
+	assert.NilError(t, err)
+	assert.Equal(t, worker.Instance, 2)
+
+	assert.NilError(t, instance.SendWorkerCommand(1, gopenqa.WorkerCommandQuit))
+	assert.DeepEqual(t, server.WorkerCommands(1), []string{gopenqa.WorkerCommandQuit})
+
+	// Only offline workers can be deleted
+	assert.Assert(t, instance.DeleteWorker(1) != nil, "deleting an online worker should fail")
+	assert.NilError(t, instance.DeleteWorker(2))
+	_, ok := server.Worker(2)
+	assert.Assert(t, !ok, "worker should be deleted")
+}
+
+func TestBroker(t *testing.T) {
+	broker := NewBroker()
+	mq, err := broker.Connect()
+	assert.NilError(t, err)
+	defer mq.Close()
+	mq.SetReconnectBackoff(10*time.Millisecond, 10*time.Millisecond)
+
+	jobs, err := mq.Subscribe("suse.openqa.job.done")
+	assert.NilError(t, err)
+	defer jobs.Close()
+	comments, err := mq.Subscribe("suse.openqa.comment.*")
+	assert.NilError(t, err)
+	defer comments.Close()
+
+	n, err := broker.PublishJSON("suse.openqa.job.done", map[string]interface{}{"id": 42, "TEST": "minimal", "result": "passed"})
+	assert.NilError(t, err)
+	assert.Equal(t, n, 1)
+	assert.Equal(t, broker.Publish("suse.openqa.job.restart", []byte(`{"id":43}`)), 0)
+	status, err := jobs.ReceiveJobStatus()
+	assert.NilError(t, err)
+	assert.Equal(t, status.Type, "job.done")
+	assert.Equal(t, status.ID, int64(42))
+	assert.Equal(t, status.Test, "minimal")
+
+	_, err = broker.PublishJSON("suse.openqa.comment.create", map[string]interface{}{"id": 7, "text": "poo#42", "job_id": 42})
+	assert.NilError(t, err)
+	comment, err := comments.ReceiveComment()
+	assert.NilError(t, err)
+	assert.Equal(t, comment.ID, 7)
+	assert.Equal(t, comment.JobID, int64(42))
+
+	// Subscriptions re-establish their queues after the connection is lost
+	closed := make(chan error, 1)
+	mq.NotifyClose(func(err error) { closed <- err })
+	states := make(chan gopenqa.RabbitMQState, 16)
+	jobs.NotifyState(func(state gopenqa.RabbitMQState, err error) { states <- state })
+	broker.Disconnect()
+	select {
+	case err := <-closed:
+		assert.ErrorContains(t, err, "CONNECTION_FORCED")
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for close notification")
+	}
+	for state := gopenqa.RabbitMQDisconnected; state != gopenqa.RabbitMQConnected; {
+		select {
+		case state = <-states:
+		case <-time.After(5 * time.Second):
+			t.Fatal("timeout waiting for reconnect")
+		}
+	}
+	_, err = broker.PublishJSON("suse.openqa.job.done", map[string]interface{}{"id": "44"})
+	assert.NilError(t, err)
+	status, err = jobs.ReceiveJobStatus()
+	assert.NilError(t, err)
+	assert.Equal(t, status.ID, int64(44))
+}
+
+type stateChange struct {
+	state gopenqa.RabbitMQState
+	err   error
+}
+
+// waitState reads state changes until the given state is reached. If refused is set, the state must be caused by a refused connection
+func waitState(t *testing.T, states chan stateChange, state gopenqa.RabbitMQState, refused bool) {
+	t.Helper()
+	for {
+		select {
+		case change := <-states:
+			if change.state == gopenqa.RabbitMQClosed {
+				t.Fatalf("subscription closed while waiting for %s", state)
+			}
+			if change.state == state && (!refused || (change.err != nil && strings.Contains(change.err.Error(), "connection refused"))) {
+				return
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timeout waiting for %s", state)
+		}
+	}
+}
+
+func TestBrokerReconnect(t *testing.T) {
+	broker := NewBroker()
+	var dials atomic.Int32
+	dial := func(remote string) (gopenqa.AMQPConnection, error) {
+		dials.Add(1)
+		return broker.Dial(remote)
+	}
+	mq, err := gopenqa.ConnectRabbitMQWithDialer("amqp://gopenqatest", dial)
+	assert.NilError(t, err)
+	defer mq.Close()
+	mq.SetReconnectBackoff(10*time.Millisecond, 20*time.Millisecond)
+
+	sub, err := mq.SubscribeWithOptions(gopenqa.RabbitMQSubscribeOptions{Keys: []string{"suse.openqa.job.*"}, Queue: "reconnect", Durable: true, ManualAck: true})
+	assert.NilError(t, err)
+	defer sub.Close()
+	states := make(chan stateChange, 1024)
+	sub.NotifyState(func(state gopenqa.RabbitMQState, err error) { states <- stateChange{state, err} })
+
+	assert.Equal(t, broker.Publish("suse.openqa.job.done", []byte(`{"id":1}`)), 1)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	d, err := sub.ReceiveContext(ctx)
+	assert.NilError(t, err)
+	assert.Equal(t, string(d.Body), `{"id":1}`)
+	assert.Assert(t, !d.Redelivered)
+
+	// Drop the connection before the message is acknowledged, while the broker refuses reconnects
+	broker.SetOffline(true)
+	broker.Disconnect()
+	waitState(t, states, gopenqa.RabbitMQDisconnected, false)
+	waitState(t, states, gopenqa.RabbitMQReconnecting, false)
+	waitState(t, states, gopenqa.RabbitMQDisconnected, true)
+	assert.Assert(t, !sub.Connected())
+	broker.SetOffline(false)
+	waitState(t, states, gopenqa.RabbitMQConnected, false)
+	assert.Assert(t, sub.Connected())
+	assert.Assert(t, dials.Load() >= 3, "expected failed dials before the reconnect, got %d dials", dials.Load())
+
+	// The unacknowledged message is delivered again on the re-established subscription, followed by new messages
+	d, err = sub.ReceiveContext(ctx)
+	assert.NilError(t, err)
+	assert.Equal(t, string(d.Body), `{"id":1}`)
+	assert.Assert(t, d.Redelivered)
+	assert.NilError(t, sub.Ack(d))
+	assert.Equal(t, broker.Publish("suse.openqa.job.restart", []byte(`{"id":2}`)), 1)
+	d, err = sub.ReceiveContext(ctx)
+	assert.NilError(t, err)
+	assert.Equal(t, string(d.Body), `{"id":2}`)
+	assert.NilError(t, sub.Ack(d))
+	assert.Equal(t, broker.Acks(), 2)
+
+	// Closing the subscription is reported and ends the reconnect loop
+	sub.Close()
+	mq.Close()
+	for closed := false; !closed; {
+		select {
+		case change := <-states:
+			closed = change.state == gopenqa.RabbitMQClosed
+		case <-time.After(5 * time.Second):
+			t.Fatal("timeout waiting for closed state")
+		}
+	}
+	_, err = sub.ReceiveContext(ctx)
+	assert.Equal(t, err, io.EOF)
+}
+
+func TestBrokerManualAck(t *testing.T) {
+	broker := NewBroker()
+	mq, err := broker.Connect()
+	assert.NilError(t, err)
+	defer mq.Close()
+
+	sub, err := mq.SubscribeWithOptions(gopenqa.RabbitMQSubscribeOptions{Keys: []string{"#"}, Queue: "gopenqa", Durable: true, ManualAck: true})
+	assert.NilError(t, err)
+	defer sub.Close()
+	_, err = broker.PublishJSON("suse.openqa.job.create", map[string]interface{}{"id": 1})
+	assert.NilError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	event, err := sub.ReceiveEventContext(ctx)
+	assert.NilError(t, err)
+	assert.Equal(t, event.Type, gopenqa.EventJobCreate)
+	// Rejected events are delivered again
+	assert.NilError(t, event.Nack(true))
+	event, err = sub.ReceiveEventContext(ctx)
+	assert.NilError(t, err)
+	assert.Equal(t, event.JobID(), int64(1))
+	assert.NilError(t, event.Ack())
+	assert.Equal(t, broker.Nacks(), 1)
+	assert.Equal(t, broker.Acks(), 1)
+}
+
+// waitClosed waits until the given event channel is closed and returns the events received until then
+func waitClosed(t *testing.T, events <-chan gopenqa.Event) []gopenqa.Event {
+	t.Helper()
+	received := make([]gopenqa.Event, 0)
+	for {
+		select {
+		case event, ok := <-events:
+			if !ok {
+				return received
+			}
+			received = append(received, event)
+		case <-time.After(5 * time.Second):
+			t.Fatal("timeout waiting for the event channel to be closed")
+		}
+	}
+}
+
+func TestBrokerCancel(t *testing.T) {
+	broker := NewBroker()
+	mq, err := broker.Connect()
+	assert.NilError(t, err)
+	defer mq.Close()
+	sub, err := mq.SubscribeWithOptions(gopenqa.RabbitMQSubscribeOptions{Keys: []string{"#"}, Queue: "cancel", ManualAck: true})
+	assert.NilError(t, err)
+	defer sub.Close()
+
+	// ReceiveContext returns the context error without a message
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err = sub.ReceiveContext(ctx)
+	assert.Equal(t, err, context.DeadlineExceeded)
+	ctx, cancel = context.WithCancel(context.Background())
+	cancel()
+	_, err = sub.ReceiveEventContext(ctx)
+	assert.Equal(t, err, context.Canceled)
+
+	// The event channel is closed when the context is cancelled. An event which is not consumed is returned to the queue
+	ctx, cancel = context.WithCancel(context.Background())
+	events := sub.Events(ctx)
+	assert.Equal(t, broker.Publish("suse.openqa.job.create", []byte(`{"id":1}`)), 1)
+	cancel()
+	for _, event := range waitClosed(t, events) {
+		// The event might have been delivered before the cancellation was noticed
+		assert.NilError(t, event.Nack(true))
+	}
+	ctx, cancel = context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	event, err := sub.ReceiveEventContext(ctx)
+	assert.NilError(t, err)
+	assert.Equal(t, event.JobID(), int64(1))
+	assert.NilError(t, event.Ack())
+
+	// Closing the subscription closes the event channel as well
+	events = sub.Events(context.Background())
+	assert.Equal(t, broker.Publish("suse.openqa.job.done", []byte(`{"id":1}`)), 1)
+	select {
+	case event := <-events:
+		assert.Equal(t, event.Type, gopenqa.EventJobDone)
+		assert.NilError(t, event.Ack())
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for event")
+	}
+	sub.Close()
+	assert.Equal(t, len(waitClosed(t, events)), 0)
+	_, err = sub.ReceiveContext(ctx)
+	assert.Equal(t, err, io.EOF)
+}
+
+func TestFederation(t *testing.T) {
+	o3 := NewServer()
+	defer o3.Close()
+	osd := NewServer()
+	defer osd.Close()
+	offline := NewServer()
+	offline.Close()
+
+	o3.AddJob(gopenqa.Job{Test: "minimal", Settings: gopenqa.Settings{Build: "20240101"}})
+	o3.AddJob(gopenqa.Job{Test: "textmode", Settings: gopenqa.Settings{Build: "20231231"}})
+	osd.AddJob(gopenqa.Job{Test: "gnome", Settings: gopenqa.Settings{Build: "20240101"}})
+	o3.AddJobGroup(gopenqa.JobGroup{Name: "openSUSE Tumbleweed"})
+	osd.AddWorker(gopenqa.Worker{Host: "worker1", Instance: 1})
+
+	federation := gopenqa.CreateFederation(o3.Instance(), osd.Instance())
+	jobs, err := federation.GetJobsByBuild("20240101", nil)
+	assert.NilError(t, err)
+	assert.Equal(t, len(jobs), 2)
+	assert.Equal(t, jobs[0].Remote, o3.URL)
+	assert.Equal(t, jobs[0].Test, "minimal")
+	assert.Equal(t, jobs[1].Remote, osd.URL)
+	assert.Equal(t, jobs[1].Test, "gnome")
+
+	// A failing instance must not fail the whole query
+	federation.Add(offline.Instance())
+	groups, err := federation.GetJobGroups()
+	assert.Equal(t, len(groups), 1)
+	assert.Equal(t, groups[0].Remote, o3.URL)
+	ferr, ok := err.(gopenqa.FederationError)
+	assert.Assert(t, ok, "expected a FederationError")
+	assert.Equal(t, len(ferr), 1)
+	assert.Equal(t, ferr[0].Remote, offline.URL)
+	workers, err := federation.GetWorkers()
+	assert.Assert(t, err != nil)
+	assert.Equal(t, len(workers), 1)
+	assert.Equal(t, workers[0].Remote, osd.URL)
+}
+
+// contains returns true if the given requests contain the given request
+func contains(requests []string, request string) bool {
+	for _, r := range requests {
+		if r == request {
+			return true
+		}
+	}
+	return false
+}
+
+func TestCopyConfiguration(t *testing.T) {
+	src := NewServer()
+	defer src.Close()
+	dst := NewServer()
+	defer dst.Close()
+
+	src.AddMachine(gopenqa.Machine{Name: "64bit", Backend: "qemu", Settings: map[string]string{"QEMUCPU": "host"}})
+	src.AddProduct(gopenqa.Product{Distri: "opensuse", Version: "Tumbleweed", Flavor: "DVD", Arch: "x86_64"})
+	src.AddTestSuite(gopenqa.TestSuite{Name: "minimal", Settings: map[string]string{"DESKTOP": "textmode"}})
+	src.AddParentJobGroup(gopenqa.JobGroup{ID: 5, Name: "openSUSE"})
+	src.AddJobGroup(gopenqa.JobGroup{ID: 7, Name: "openSUSE Tumbleweed", ParentID: 5})
+	src.AddJobGroup(gopenqa.JobGroup{ID: 8, Name: "openSUSE Leap"})
+	yaml := "products:\n  opensuse-Tumbleweed-DVD-x86_64: {}\n"
+	assert.NilError(t, src.Instance().PostJobTemplateYAML(7, yaml))
+	dst.AddMachine(gopenqa.Machine{Name: "64bit", Backend: "svirt"})
+	dst.AddParentJobGroup(gopenqa.JobGroup{Name: "Development"})
+
+	report, err := gopenqa.CopyConfiguration(src.Instance(), dst.Instance(), gopenqa.ImportOptions{JobGroups: []int{7}})
+	assert.NilError(t, err)
+	assert.Equal(t, report.Count(gopenqa.ImportCreated), 5)
+	assert.Equal(t, report.Count(gopenqa.ImportSkipped), 1)
+	assert.Equal(t, report.ParentGroups[5], 2)
+	groups, err := dst.Instance().GetJobGroups()
+	assert.NilError(t, err)
+	assert.Equal(t, len(groups), 1)
+	assert.Equal(t, groups[0].Name, "openSUSE Tumbleweed")
+	assert.Equal(t, groups[0].ParentID, 2)
+	assert.Equal(t, dst.SchedulingYAML(groups[0].ID), yaml)
+	machines, err := dst.Instance().GetMachines()
+	assert.NilError(t, err)
+	assert.Equal(t, machines[0].Backend, "svirt")
+
+	// Copying again doesn't change anything, unless existing entities are updated
+	report, err = gopenqa.CopyConfiguration(src.Instance(), dst.Instance(), gopenqa.ImportOptions{})
+	assert.NilError(t, err)
+	assert.Equal(t, report.Count(gopenqa.ImportCreated), 1) // openSUSE Leap
+	assert.Equal(t, report.Count(gopenqa.ImportUpdated), 0)
+	src.AddProduct(gopenqa.Product{ID: 1, Distri: "opensuse", Version: "Tumbleweed", Flavor: "DVD", Arch: "x86_64", Settings: map[string]string{"ISO_MAXSIZE": "4700372992"}})
+	src.AddTestSuite(gopenqa.TestSuite{ID: 1, Name: "minimal", Settings: map[string]string{"DESKTOP": "minimalx"}})
+	report, err = gopenqa.CopyConfiguration(src.Instance(), dst.Instance(), gopenqa.ImportOptions{Update: true})
+	assert.NilError(t, err)
+	assert.Equal(t, report.Count(gopenqa.ImportCreated), 0)
+	machines, err = dst.Instance().GetMachines()
+	assert.NilError(t, err)
+	assert.Equal(t, machines[0].Backend, "qemu")
+	assert.Equal(t, machines[0].Settings["QEMUCPU"], "host")
+	products, err := dst.Instance().GetProducts()
+	assert.NilError(t, err)
+	assert.Equal(t, products[0].Settings["ISO_MAXSIZE"], "4700372992")
+	suites, err := dst.Instance().GetTestSuites()
+	assert.NilError(t, err)
+	assert.Equal(t, suites[0].Settings["DESKTOP"], "minimalx")
+	// Like openQA, the fake server only accepts updates via PUT
+	for _, request := range []string{"PUT /api/v1/machines/1", "PUT /api/v1/products/1", "PUT /api/v1/test_suites/1"} {
+		assert.Assert(t, contains(dst.Requests(), request), request)
+	}
+	status, _, err := dst.Instance().Do("POST", "/api/v1/test_suites/1", "", nil)
+	assert.NilError(t, err)
+	assert.Equal(t, status, 405)
+}
+
+func TestBackupRestore(t *testing.T) {
+	src := NewServer()
+	defer src.Close()
+	dst := NewServer()
+	defer dst.Close()
+
+	src.AddMachine(gopenqa.Machine{Name: "64bit", Backend: "qemu"})
+	src.AddTestSuite(gopenqa.TestSuite{Name: "minimal"})
+	src.AddParentJobGroup(gopenqa.JobGroup{ID: 3, Name: "openSUSE"})
+	src.AddJobGroup(gopenqa.JobGroup{ID: 7, Name: "openSUSE Tumbleweed", ParentID: 3})
+	assert.NilError(t, src.Instance().PostJobTemplateYAML(7, "defaults: {}\n"))
+	src.AddJobGroupComment(7, gopenqa.Comment{Text: "Tracked in poo#42"})
+	_, err := src.Instance().PostParentJobGroupComment(3, "Parent comment")
+	assert.NilError(t, err)
+
+	backup, err := src.Instance().CreateBackup()
+	assert.NilError(t, err)
+	dir := t.TempDir()
+	for _, path := range []string{dir + "/backup", dir + "/backup.tar.gz"} {
+		assert.NilError(t, backup.Write(path))
+		restored, err := gopenqa.ReadBackup(path)
+		assert.NilError(t, err)
+		assert.Equal(t, restored.Version, gopenqa.BackupVersion)
+		assert.Equal(t, restored.Remote, src.URL)
+		assert.DeepEqual(t, restored.Configuration.SchedulingYAML, map[int]string{7: "defaults: {}\n"})
+		assert.Equal(t, restored.GroupComments[7][0].Text, "Tracked in poo#42")
+
+		// Restoring is idempotent
+		report, err := dst.Instance().RestoreBackup(restored, gopenqa.ImportOptions{})
+		assert.NilError(t, err)
+		if path == dir+"/backup" {
+			assert.Equal(t, report.Count(gopenqa.ImportCreated), 7)
+		} else {
+			assert.Equal(t, report.Count(gopenqa.ImportCreated), 0)
+		}
+	}
+	// Backups are not mixed with existing files, but empty directories can be used
+	assert.ErrorContains(t, backup.Write(dir+"/backup"), "not empty")
+	archive, err := os.ReadFile(dir + "/backup.tar.gz")
+	assert.NilError(t, err)
+	assert.ErrorContains(t, backup.Write(dir+"/backup.tar.gz"), "already exists")
+	unchanged, err := os.ReadFile(dir + "/backup.tar.gz")
+	assert.NilError(t, err)
+	assert.DeepEqual(t, unchanged, archive)
+	assert.NilError(t, os.Mkdir(dir+"/empty", 0755))
+	assert.NilError(t, backup.Write(dir+"/empty"))
+	entries, err := os.ReadDir(dir)
+	assert.NilError(t, err)
+	assert.Equal(t, len(entries), 3, "no temporary directories should be left")
+	restored, err := gopenqa.ReadBackup(dir + "/empty")
+	assert.NilError(t, err)
+	assert.Equal(t, len(restored.Configuration.JobGroups), 1)
+
+	comments, err := dst.Instance().GetJobGroupComments(1)
+	assert.NilError(t, err)
+	assert.Equal(t, len(comments), 1)
+	assert.Equal(t, comments[0].Text, "Tracked in poo#42")
+	comments, err = dst.Instance().GetParentJobGroupComments(1)
+	assert.NilError(t, err)
+	assert.Equal(t, len(comments), 1)
+	group, err := dst.Instance().GetJobGroup(1)
+	assert.NilError(t, err)
+	assert.Equal(t, group.ParentID, 1)
+}
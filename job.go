@@ -1,6 +1,10 @@
 package gopenqa
 
-import "fmt"
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+)
 
 /* Job instance */
 type Job struct {
@@ -22,9 +26,9 @@ type Job struct {
 	Tstarted  string   `json:"t_started"`
 	Test      string   `json:"test"`
 	/* this is added by the program and not part of the fetched json */
-	Link     string
+	Link     string `json:"link"`
 	Prefix   string
-	Remote   string // openQA remote host
+	Remote   string `json:"remote"` // openQA remote host
 	instance *Instance
 }
 
@@ -40,6 +44,10 @@ type Settings struct {
 	Arch    string `json:"ARCH"`
 	Backend string `json:"BACKEND"`
 	Machine string `json:"MACHINE"`
+	Build   string `json:"BUILD"`
+	Distri  string `json:"DISTRI"`
+	Flavor  string `json:"FLAVOR"`
+	Version string `json:"VERSION"`
 }
 
 /* Special struct for getting quick job status */
@@ -49,6 +57,49 @@ type JobState struct {
 	State     string `json:"state"`
 }
 
+/* JobQuery selects jobs by their settings, state and result. Empty fields match all jobs */
+type JobQuery struct {
+	Distri       string
+	Version      string
+	Flavor       string
+	Arch         string
+	Build        string
+	GroupID      int
+	Test         string
+	State        []string // Accepted job states (e.g. "scheduled", "running", "done")
+	Result       []string // Accepted job results (e.g. "passed", "failed")
+	Latest       bool     // Only the latest job per scenario
+	FollowClones bool     // Replace cloned jobs by their most recent clone
+	Limit        int      // Maximum number of jobs returned by openQA, 0 for the server default
+}
+
+// encode returns the URL query of the job query
+func (q *JobQuery) encode() string {
+	values := url.Values{}
+	params := map[string]string{"distri": q.Distri, "version": q.Version, "flavor": q.Flavor, "arch": q.Arch, "build": q.Build, "test": q.Test}
+	for k, v := range params {
+		if v != "" {
+			values.Set(k, v)
+		}
+	}
+	if q.GroupID != 0 {
+		values.Set("groupid", strconv.Itoa(q.GroupID))
+	}
+	for _, state := range q.State {
+		values.Add("state", state)
+	}
+	for _, result := range q.Result {
+		values.Add("result", result)
+	}
+	if q.Latest {
+		values.Set("latest", "1")
+	}
+	if q.Limit > 0 {
+		values.Set("limit", strconv.Itoa(q.Limit))
+	}
+	return values.Encode()
+}
+
 /* Format job as a string */
 func (j *Job) String() string {
 	return fmt.Sprintf("%d %s (%s)", j.ID, j.Name, j.Test)
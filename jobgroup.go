@@ -13,7 +13,7 @@ type JobGroup struct {
 	Description      string `json:"description"`
 	BuildVersionSort int    `json:"build_version_sort"`
 	CarryOverBugrefs int    `json:"carry_over_bugrefs"`
-	DefaultPriority  int    `json:"default_priority`
+	DefaultPriority  int    `json:"default_priority"`
 	// Disabled because of type mismatch in json
 	// Sometimes it's returned as int, sometimes as string and we cannot deal with that atm
 	//KeepImportantLogsInDays    int    `json:"keep_important_logs_in_days"`
@@ -23,6 +23,8 @@ type JobGroup struct {
 	//SizeLimit                  int    `json:"size_limit_gb"` // Size limit in GB
 	SortOrder int    `json:"sort_order"`
 	Template  string `json:"template"`
+	/* this is added by the program and not part of the fetched json */
+	Remote string `json:"remote"` // openQA remote host
 }
 
 func addIntIfNotZero(value int, name string, values *url.Values) {
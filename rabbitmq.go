@@ -3,10 +3,14 @@
 package gopenqa
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	amqp "github.com/rabbitmq/amqp091-go"
 )
@@ -30,25 +34,99 @@ type JobStatus struct {
 
 // RabbitMQ comment
 type CommentMQ struct {
-	ID      int    `json:"id"`
-	Created string `json:"created"`
-	Updates string `json:"updated"`
-	Text    string `json:"text"`
-	User    string `json:"user"`
+	ID            int    `json:"id"`
+	Created       string `json:"created"`
+	Updates       string `json:"updated"`
+	Text          string `json:"text"`
+	User          string `json:"user"`
+	JobID         int64  `json:"job_id"`          // Set for job comments
+	GroupID       int    `json:"group_id"`        // Set for job group comments
+	ParentGroupID int    `json:"parent_group_id"` // Set for parent job group comments
+}
+
+// AMQPConnection is a connection to an AMQP broker. It is implemented by the amqp091-go connection and allows to replace the broker, e.g. by an in-memory broker in tests
+type AMQPConnection interface {
+	Channel() (AMQPChannel, error)
+	IsClosed() bool
+	Close() error
+	NotifyClose(receiver chan *amqp.Error) chan *amqp.Error
+}
+
+// AMQPChannel is the subset of the amqp091-go channel methods, which are used for subscriptions
+type AMQPChannel interface {
+	Qos(prefetchCount, prefetchSize int, global bool) error
+	QueueDeclare(name string, durable, autoDelete, exclusive, noWait bool, args amqp.Table) (amqp.Queue, error)
+	QueueBind(name, key, exchange string, noWait bool, args amqp.Table) error
+	Consume(queue, consumer string, autoAck, exclusive, noLocal, noWait bool, args amqp.Table) (<-chan amqp.Delivery, error)
+	Close() error
+}
+
+// AMQPDialer establishes a new connection to the given remote
+type AMQPDialer func(remote string) (AMQPConnection, error)
+
+// amqpConnection wraps the amqp091-go connection as AMQPConnection
+type amqpConnection struct {
+	*amqp.Connection
+}
+
+func (con amqpConnection) Channel() (AMQPChannel, error) {
+	return con.Connection.Channel()
+}
+
+// DialAMQP connects to the given RabbitMQ server. This is the default dialer
+func DialAMQP(remote string) (AMQPConnection, error) {
+	con, err := amqp.Dial(remote)
+	if err != nil {
+		return nil, err
+	}
+	return amqpConnection{con}, nil
 }
 
 // RabbitMQ struct is the object which handles the connection to a RabbitMQ instance
 type RabbitMQ struct {
-	remote string
-	con    *amqp.Connection
-	closed bool
+	remote     string
+	dial       AMQPDialer
+	con        AMQPConnection
+	closed     bool
+	minBackoff time.Duration // Initial delay between reconnection attempts of subscriptions
+	maxBackoff time.Duration // Maximum delay between reconnection attempts of subscriptions
+	mutex      sync.Mutex    // Guards the connection, as multiple subscriptions might reconnect at the same time
 }
 
 // Callback when the connection was closed
 type RabbitMQCloseCallback func(error)
 
+// RabbitMQState is the connection state of a subscription
+type RabbitMQState int
+
+const (
+	RabbitMQConnected    RabbitMQState = iota // Subscription is connected and receives messages
+	RabbitMQDisconnected                      // Connection has been lost, a reconnect will be attempted
+	RabbitMQReconnecting                      // Subscription is trying to re-establish the connection
+	RabbitMQClosed                            // Subscription or connection has been closed and won't reconnect
+)
+
+func (state RabbitMQState) String() string {
+	switch state {
+	case RabbitMQConnected:
+		return "connected"
+	case RabbitMQDisconnected:
+		return "disconnected"
+	case RabbitMQReconnecting:
+		return "reconnecting"
+	case RabbitMQClosed:
+		return "closed"
+	}
+	return "unknown"
+}
+
+// Callback when the connection state of a subscription changes. err is set, if the change was caused by an error
+type RabbitMQStateCallback func(state RabbitMQState, err error)
+
 // Close connection
 func (mq *RabbitMQ) Close() {
+	mq.mutex.Lock()
+	defer mq.mutex.Unlock()
 	mq.closed = true
 	if mq.con != nil {
 		mq.con.Close()
@@ -57,68 +135,257 @@ func (mq *RabbitMQ) Close() {
 
 // Connected returns true if RabbitMQ is connected
 func (mq *RabbitMQ) Connected() bool {
+	mq.mutex.Lock()
+	defer mq.mutex.Unlock()
 	return !mq.closed && (mq.con != nil) && !mq.con.IsClosed()
 }
 
 // Connected returns true if RabbitMQ is closing or if it is closed.
 func (mq *RabbitMQ) Closed() bool {
-	if mq.closed {
-		return true
-	}
-	if mq.con == nil || mq.con.IsClosed() {
-		mq.closed = true
-		return true
-	}
-	return false
+	mq.mutex.Lock()
+	defer mq.mutex.Unlock()
+	// Note: A lost connection doesn't mark mq as closed, because subscriptions will try to reconnect
+	return mq.closed || mq.con == nil || mq.con.IsClosed()
+}
+
+// closedByUser returns true, if the connection has been closed via Close
+func (mq *RabbitMQ) closedByUser() bool {
+	mq.mutex.Lock()
+	defer mq.mutex.Unlock()
+	return mq.closed
 }
 
 // Reconnect to the RabbitMQ server. This will close any previous connections and channels
+// Existing subscriptions will re-establish their channels on the new connection
 func (mq *RabbitMQ) Reconnect() error {
 	var err error
+	mq.mutex.Lock()
+	defer mq.mutex.Unlock()
 	if mq.con != nil {
 		mq.con.Close()
 	}
 	mq.closed = false
-	mq.con, err = amqp.Dial(mq.remote)
+	mq.con, err = mq.dialer()(mq.remote)
 	return err
 }
 
+// SetReconnectBackoff sets the initial and the maximum delay between reconnection attempts of subscriptions. The delay is doubled after each failed attempt
+func (mq *RabbitMQ) SetReconnectBackoff(min time.Duration, max time.Duration) {
+	mq.mutex.Lock()
+	defer mq.mutex.Unlock()
+	mq.minBackoff = min
+	mq.maxBackoff = max
+}
+
+// connection returns the current connection, or re-establishes it, if it has been lost
+func (mq *RabbitMQ) connection() (AMQPConnection, error) {
+	var err error
+	mq.mutex.Lock()
+	defer mq.mutex.Unlock()
+	if mq.closed {
+		return nil, fmt.Errorf("connection closed")
+	}
+	// Either only the channel has been lost or another subscription has reconnected already
+	if mq.con != nil && !mq.con.IsClosed() {
+		return mq.con, nil
+	}
+	mq.con, err = mq.dialer()(mq.remote)
+	return mq.con, err
+}
+
+// dialer returns the dialer of the connection. RabbitMQ objects not created via ConnectRabbitMQWithDialer use DialAMQP
+func (mq *RabbitMQ) dialer() AMQPDialer {
+	if mq.dial == nil {
+		return DialAMQP
+	}
+	return mq.dial
+}
+
+func (mq *RabbitMQ) backoff() (time.Duration, time.Duration) {
+	mq.mutex.Lock()
+	defer mq.mutex.Unlock()
+	return mq.minBackoff, mq.maxBackoff
+}
+
 // NotifyClose registeres a defined callback function for when the RabbitMQ connection is closed
 func (mq *RabbitMQ) NotifyClose(callback RabbitMQCloseCallback) {
+	mq.mutex.Lock()
+	con := mq.con
+	mq.mutex.Unlock()
+	if con == nil {
+		return
+	}
+	// Register before returning, so that no close event is missed
+	recvChannel := make(chan *amqp.Error, 1)
+	con.NotifyClose(recvChannel)
 	go func() {
-		recvChannel := make(chan *amqp.Error, 1)
-		mq.con.NotifyClose(recvChannel)
 		for err := range recvChannel {
 			callback(fmt.Errorf(err.Error()))
 		}
 	}()
 }
 
+// RabbitMQSubscribeOptions configure the exchange, queue and acknowledgement mode of a subscription
+type RabbitMQSubscribeOptions struct {
+	Keys       []string // Binding keys of the subscription
+	Exchange   string   // Exchange to bind to. Defaults to "pubsub"
+	Queue      string   // Name of the queue. An empty name creates an anonymous, exclusive and auto-delete queue
+	Durable    bool     // Declare the named queue as durable, so that messages are kept while no consumer is connected
+	AutoDelete bool     // Delete the named queue, when the last consumer unsubscribes
+	Prefetch   int      // Maximum number of unacknowledged messages delivered at a time (0 for unlimited)
+	ManualAck  bool     // Messages need to be acknowledged via Ack or Nack
+}
+
 // RabbitMQSubscription handles a single subscription
 type RabbitMQSubscription struct {
-	channel *amqp.Channel
-	key     string
-	obs     <-chan amqp.Delivery
-	mq      *RabbitMQ
-	con     *amqp.Connection // Keep a reference to the connection to check if it is still connected. This is necessary because mq can reconnect and therefore have another new mq.con instance
+	opts RabbitMQSubscribeOptions
+	obs  chan amqp.Delivery // Messages of all (re-established) channels are delivered here
+	mq   *RabbitMQ
+	link *rabbitMQLink
+}
+
+// rabbitMQLink holds the state of a subscription, which changes when it reconnects
+type rabbitMQLink struct {
+	channel   AMQPChannel
+	con       AMQPConnection // Keep a reference to the connection to check if it is still connected. This is necessary because mq can reconnect and therefore have another new mq.con instance
+	closed    bool
+	done      chan struct{} // Closed when the subscription is closed
+	callbacks []RabbitMQStateCallback
+	filters   []EventPredicate
+	instance  *Instance // Instance used to enrich received jobs and events, if set
+	mutex     sync.Mutex
 }
 
 // Connected returns true if RabbitMQ is connected
 func (sub *RabbitMQSubscription) Connected() bool {
-	return !sub.con.IsClosed()
+	sub.link.mutex.Lock()
+	defer sub.link.mutex.Unlock()
+	return !sub.link.closed && sub.link.con != nil && !sub.link.con.IsClosed()
+}
+
+// NotifyState registers a callback function for connection state changes of this subscription
+func (sub *RabbitMQSubscription) NotifyState(callback RabbitMQStateCallback) {
+	sub.link.mutex.Lock()
+	defer sub.link.mutex.Unlock()
+	sub.link.callbacks = append(sub.link.callbacks, callback)
+}
+
+// SetInstance binds the subscription to the openQA instance, which publishes the messages
+// Received jobs and events are then attached to the instance, so that further information can be fetched from it
+func (sub *RabbitMQSubscription) SetInstance(instance *Instance) {
+	sub.link.mutex.Lock()
+	defer sub.link.mutex.Unlock()
+	sub.link.instance = instance
+}
+
+func (sub *RabbitMQSubscription) boundInstance() *Instance {
+	sub.link.mutex.Lock()
+	defer sub.link.mutex.Unlock()
+	return sub.link.instance
+}
+
+// AddFilter attaches a predicate to the subscription. Only messages which satisfy all predicates are delivered, all others are dropped
+func (sub *RabbitMQSubscription) AddFilter(predicate EventPredicate) {
+	sub.link.mutex.Lock()
+	defer sub.link.mutex.Unlock()
+	sub.link.filters = append(sub.link.filters, predicate)
+}
+
+// SetFilter attaches the given filter to the subscription. Returns an error if the filter contains invalid patterns
+func (sub *RabbitMQSubscription) SetFilter(filter EventFilter) error {
+	predicate, err := filter.Predicate()
+	if err != nil {
+		return err
+	}
+	sub.AddFilter(predicate)
+	return nil
+}
+
+// accept returns true, if the given message satisfies all filters of the subscription
+func (sub *RabbitMQSubscription) accept(d amqp.Delivery) bool {
+	sub.link.mutex.Lock()
+	filters := sub.link.filters
+	sub.link.mutex.Unlock()
+	if len(filters) == 0 {
+		return true
+	}
+	// Events which cannot be parsed are still checked by their routing key
+	event, _ := parseEvent(d)
+	for _, filter := range filters {
+		if !filter(event) {
+			return false
+		}
+	}
+	return true
+}
+
+func (sub *RabbitMQSubscription) setState(state RabbitMQState, err error) {
+	sub.link.mutex.Lock()
+	callbacks := sub.link.callbacks
+	sub.link.mutex.Unlock()
+	for _, callback := range callbacks {
+		callback(state, err)
+	}
+}
+
+func (sub *RabbitMQSubscription) closed() bool {
+	sub.link.mutex.Lock()
+	defer sub.link.mutex.Unlock()
+	return sub.link.closed
+}
+
+// Ack acknowledges the given message. This is only required if the subscription uses ManualAck
+func (sub *RabbitMQSubscription) Ack(d amqp.Delivery) error {
+	if !sub.opts.ManualAck {
+		return nil
+	}
+	return d.Ack(false)
+}
+
+// Nack rejects the given message. If requeue is true, the message will be delivered again. This is only required if the subscription uses ManualAck
+func (sub *RabbitMQSubscription) Nack(d amqp.Delivery, requeue bool) error {
+	if !sub.opts.ManualAck {
+		return nil
+	}
+	return d.Nack(false, requeue)
+}
+
+// acknowledge acknowledges messages which are consumed by the typed receivers, as the caller has no access to the raw message
+// Messages which cannot be parsed are rejected without requeuing them
+func (sub *RabbitMQSubscription) acknowledge(d amqp.Delivery, err error) {
+	if err != nil {
+		sub.Nack(d, false)
+	} else {
+		sub.Ack(d)
+	}
 }
 
 // Receive receives a raw non-empty RabbitMQ messages
 func (sub *RabbitMQSubscription) Receive() (amqp.Delivery, error) {
-	for msg, ok := <-sub.obs; ok; {
-		if len(msg.Body) > 0 {
-			return msg, nil
+	return sub.ReceiveContext(context.Background())
+}
+
+// ReceiveContext receives a raw non-empty RabbitMQ message or returns the context error, if the context is done first
+// Returns io.EOF once the subscription has been closed
+func (sub *RabbitMQSubscription) ReceiveContext(ctx context.Context) (amqp.Delivery, error) {
+	for {
+		select {
+		case msg, ok := <-sub.obs:
+			if !ok {
+				if sub.mq == nil || sub.closed() || sub.mq.closedByUser() {
+					return amqp.Delivery{}, io.EOF
+				}
+				return amqp.Delivery{}, fmt.Errorf("channel unexpectedly closed")
+			}
+			if len(msg.Body) > 0 {
+				return msg, nil
+			}
+			// Skip empty messages
+			sub.Ack(msg)
+		case <-ctx.Done():
+			return amqp.Delivery{}, ctx.Err()
 		}
 	}
-	if sub.mq == nil || sub.mq.closed || sub.con == nil || sub.con.IsClosed() {
-		return amqp.Delivery{}, fmt.Errorf("EOF")
-	}
-	return amqp.Delivery{}, fmt.Errorf("channel unexpectedly closed")
 }
 
 // ReceiveJob receives the next message and try to parse it as job
@@ -130,6 +397,7 @@ func (sub *RabbitMQSubscription) ReceiveJob() (Job, error) {
 	}
 	// Try to unmarshall to json
 	err = json.Unmarshal(d.Body, &job)
+	sub.acknowledge(d, err)
 	if err != nil {
 		return job, err
 	}
@@ -137,17 +405,27 @@ func (sub *RabbitMQSubscription) ReceiveJob() (Job, error) {
 	if strings.HasSuffix(d.RoutingKey, ".job.done") && job.State == "" {
 		job.State = "done"
 	}
+	if instance := sub.boundInstance(); instance != nil {
+		job.applyInstance(instance)
+	}
 
 	return job, err
 }
 
 // ReceiveJobStatus receives the next message and try to parse it as JobStatus. Use this for job status updates
 func (sub *RabbitMQSubscription) ReceiveJobStatus() (JobStatus, error) {
-	var status JobStatus
 	d, err := sub.Receive()
 	if err != nil {
-		return status, err
+		return JobStatus{}, err
 	}
+	status, err := parseJobStatus(d)
+	sub.acknowledge(d, err)
+	return status, err
+}
+
+// parseJobStatus parses the given message as JobStatus
+func parseJobStatus(d amqp.Delivery) (JobStatus, error) {
+	var status JobStatus
 
 	// Required due to poo#114529
 	type IJobStatus struct {
@@ -167,7 +445,7 @@ func (sub *RabbitMQSubscription) ReceiveJobStatus() (JobStatus, error) {
 	}
 	// Try to unmarshall to json
 	var istatus IJobStatus
-	err = json.Unmarshal(d.Body, &istatus)
+	err := json.Unmarshal(d.Body, &istatus)
 	if err != nil {
 		return status, err
 	}
@@ -207,7 +485,7 @@ func (sub *RabbitMQSubscription) ReceiveJobStatus() (JobStatus, error) {
 	return status, nil
 }
 
-// ReceiveJobStatus receives the next message and try to parse it as Comment. Use this for job status updates
+// ReceiveComment receives the next message and try to parse it as Comment. Use this for comment updates
 func (sub *RabbitMQSubscription) ReceiveComment() (CommentMQ, error) {
 	var comment CommentMQ
 	d, err := sub.Receive()
@@ -216,59 +494,225 @@ func (sub *RabbitMQSubscription) ReceiveComment() (CommentMQ, error) {
 	}
 	// Try to unmarshall to json
 	err = json.Unmarshal(d.Body, &comment)
+	sub.acknowledge(d, err)
 	if err != nil {
 		return comment, err
 	}
 	return comment, err
 }
 
+// ReceiveEvent receives the next message and parses it according to its routing key
+// Messages of unknown types are returned with only their raw payload set
+// With ManualAck, the event must be acknowledged via Event.Ack or Event.Nack
+func (sub *RabbitMQSubscription) ReceiveEvent() (Event, error) {
+	return sub.ReceiveEventContext(context.Background())
+}
+
+// ReceiveEventContext receives the next event like ReceiveEvent or returns the context error, if the context is done first
+func (sub *RabbitMQSubscription) ReceiveEventContext(ctx context.Context) (Event, error) {
+	d, err := sub.ReceiveContext(ctx)
+	if err != nil {
+		return Event{}, err
+	}
+	return sub.event(d)
+}
+
+// event parses the given message as Event and attaches it for acknowledgement
+func (sub *RabbitMQSubscription) event(d amqp.Delivery) (Event, error) {
+	event, err := parseEvent(d)
+	if sub.opts.ManualAck {
+		event.delivery = &d
+	}
+	event.instance = sub.boundInstance()
+	return event, err
+}
+
+// Events delivers all events of this subscription on the returned channel, for usage in select statements
+// The channel is closed when the context is done or the subscription is closed. Events whose payload cannot be parsed are delivered with only their raw payload set
+func (sub *RabbitMQSubscription) Events(ctx context.Context) <-chan Event {
+	events := make(chan Event)
+	go func() {
+		defer close(events)
+		for {
+			d, err := sub.ReceiveContext(ctx)
+			if err != nil {
+				return
+			}
+			event, _ := sub.event(d)
+			select {
+			case events <- event:
+			case <-ctx.Done():
+				// The event has been received but not delivered. Return it to the queue, if possible
+				event.Nack(true)
+				return
+			}
+		}
+	}()
+	return events
+}
+
 // Close subscription channel
 func (sub *RabbitMQSubscription) Close() {
-	sub.channel.Close()
+	sub.link.mutex.Lock()
+	defer sub.link.mutex.Unlock()
+	if sub.link.closed {
+		return
+	}
+	sub.link.closed = true
+	close(sub.link.done)
+	if sub.link.channel != nil {
+		sub.link.channel.Close()
+	}
 }
 
 // ConnectRabbitMQ connects to a RabbitMQ instance and returns the RabbitMQ object
 func ConnectRabbitMQ(remote string) (RabbitMQ, error) {
-	var err error
-	rmq := RabbitMQ{remote: remote, closed: false}
-
-	rmq.con, err = amqp.Dial(remote)
-	if err != nil {
-		return rmq, err
-	}
+	return ConnectRabbitMQWithDialer(remote, DialAMQP)
+}
 
-	return rmq, nil
+// ConnectRabbitMQWithDialer connects to a RabbitMQ instance using the given dialer, which is also used for reconnects
+// Use this to connect to an alternative broker implementation, e.g. an in-memory broker in tests
+func ConnectRabbitMQWithDialer(remote string, dial AMQPDialer) (RabbitMQ, error) {
+	con, err := dial(remote)
+	return RabbitMQ{remote: remote, dial: dial, con: con, closed: false, minBackoff: 1 * time.Second, maxBackoff: 60 * time.Second}, err
 }
 
 // Subscribe to a given key and get the messages via the callback function.
 // This method will return after establishing the channel and call the callback function when a new message arrives
 // This message returns a RabbitMQSubscription object, which in turn can be used to receive the incoming messages
+// If the connection is lost, the subscription re-establishes its channel, queue and binding and continues to deliver messages
 func (mq *RabbitMQ) Subscribe(key string) (RabbitMQSubscription, error) {
-	var sub RabbitMQSubscription
-	ch, err := mq.con.Channel()
+	return mq.SubscribeWithOptions(RabbitMQSubscribeOptions{Keys: []string{key}})
+}
+
+// SubscribeWithOptions creates a subscription on the given exchange and queue for all given binding keys.
+// Use this for durable queues and manual acknowledgement of messages
+func (mq *RabbitMQ) SubscribeWithOptions(opts RabbitMQSubscribeOptions) (RabbitMQSubscription, error) {
+	if opts.Exchange == "" {
+		opts.Exchange = "pubsub"
+	}
+	sub := RabbitMQSubscription{opts: opts, mq: mq, obs: make(chan amqp.Delivery)}
+	if len(opts.Keys) == 0 {
+		return sub, fmt.Errorf("no binding keys given")
+	}
+	sub.link = &rabbitMQLink{done: make(chan struct{})}
+	mq.mutex.Lock()
+	con := mq.con
+	mq.mutex.Unlock()
+	if con == nil {
+		return sub, fmt.Errorf("not connected")
+	}
+	deliveries, err := sub.setup(con)
 	if err != nil {
 		return sub, err
 	}
+	go sub.run(deliveries)
+	return sub, nil
+}
+
+// setup establishes the channel, queue and binding of this subscription on the given connection
+func (sub *RabbitMQSubscription) setup(con AMQPConnection) (<-chan amqp.Delivery, error) {
+	ch, err := con.Channel()
+	if err != nil {
+		return nil, err
+	}
+
+	if sub.opts.Prefetch > 0 {
+		if err := ch.Qos(sub.opts.Prefetch, 0, false); err != nil {
+			ch.Close()
+			return nil, err
+		}
+	}
 
 	// Create message queue and receive channel
-	// Create a new exclusive queue with auto-delete
-	q, err := ch.QueueDeclare("", false, false, true, true, nil)
+	var q amqp.Queue
+	if sub.opts.Queue == "" {
+		// Create a new exclusive queue with auto-delete
+		q, err = ch.QueueDeclare("", false, false, true, true, nil)
+	} else {
+		// Named queues are not exclusive, so that they can be reused after a reconnect
+		q, err = ch.QueueDeclare(sub.opts.Queue, sub.opts.Durable, sub.opts.AutoDelete, false, false, nil)
+	}
 	if err != nil {
 		ch.Close()
-		return sub, err
+		return nil, err
 	}
-	if err := ch.QueueBind(q.Name, key, "pubsub", false, nil); err != nil {
-		ch.Close()
-		return sub, err
+	for _, key := range sub.opts.Keys {
+		if err := ch.QueueBind(q.Name, key, sub.opts.Exchange, false, nil); err != nil {
+			ch.Close()
+			return nil, err
+		}
 	}
-	obs, err := ch.Consume(q.Name, "", true, false, false, false, nil)
+	obs, err := ch.Consume(q.Name, "", !sub.opts.ManualAck, false, false, false, nil)
 	if err != nil {
 		ch.Close()
-		return sub, err
+		return nil, err
+	}
+
+	sub.link.mutex.Lock()
+	defer sub.link.mutex.Unlock()
+	if sub.link.closed {
+		ch.Close()
+		return nil, fmt.Errorf("subscription closed")
+	}
+	sub.link.channel = ch
+	sub.link.con = con
+	return obs, nil
+}
+
+// run forwards the received messages to sub.obs and reconnects, if the channel is lost
+func (sub *RabbitMQSubscription) run(deliveries <-chan amqp.Delivery) {
+	defer close(sub.obs)
+	for {
+		for d := range deliveries {
+			if !sub.accept(d) {
+				// Filtered messages are dropped
+				sub.Ack(d)
+				continue
+			}
+			select {
+			case sub.obs <- d:
+			case <-sub.link.done:
+				return
+			}
+		}
+		if sub.closed() || sub.mq.closedByUser() {
+			sub.setState(RabbitMQClosed, nil)
+			return
+		}
+		sub.setState(RabbitMQDisconnected, fmt.Errorf("channel closed"))
+		if deliveries = sub.reconnect(); deliveries == nil {
+			sub.setState(RabbitMQClosed, nil)
+			return
+		}
+		sub.setState(RabbitMQConnected, nil)
+	}
+}
+
+// reconnect re-establishes the subscription with exponential backoff. Returns nil if the subscription has been closed in the meantime
+func (sub *RabbitMQSubscription) reconnect() <-chan amqp.Delivery {
+	backoff, maxBackoff := sub.mq.backoff()
+	for {
+		select {
+		case <-time.After(backoff):
+		case <-sub.link.done:
+			return nil
+		}
+		sub.setState(RabbitMQReconnecting, nil)
+		con, err := sub.mq.connection()
+		if err == nil {
+			var deliveries <-chan amqp.Delivery
+			if deliveries, err = sub.setup(con); err == nil {
+				return deliveries
+			}
+		}
+		if sub.closed() || sub.mq.closedByUser() {
+			return nil
+		}
+		sub.setState(RabbitMQDisconnected, err)
+		backoff *= 2
+		if backoff > maxBackoff {
+			backoff = maxBackoff
+		}
 	}
-	sub.channel = ch
-	sub.key = key
-	sub.obs = obs
-	sub.con = mq.con
-	return sub, nil
 }
@@ -1,5 +1,33 @@
 package gopenqa
 
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Commands which can be sent to a worker via SendWorkerCommand
+const (
+	WorkerCommandQuit                  = "quit"
+	WorkerCommandAbort                 = "abort"
+	WorkerCommandSchedulerAbort        = "scheduler_abort"
+	WorkerCommandCancel                = "cancel"
+	WorkerCommandObsolete              = "obsolete"
+	WorkerCommandLivelogStart          = "livelog_start"
+	WorkerCommandLivelogStop           = "livelog_stop"
+	WorkerCommandDeveloperSessionStart = "developer_session_start"
+)
+
+/* IsWorkerCommand returns true, if the given command is known to openQA */
+func IsWorkerCommand(command string) bool {
+	switch command {
+	case WorkerCommandQuit, WorkerCommandAbort, WorkerCommandSchedulerAbort, WorkerCommandCancel, WorkerCommandObsolete, WorkerCommandLivelogStart, WorkerCommandLivelogStop, WorkerCommandDeveloperSessionStart:
+		return true
+	}
+	return false
+}
+
 /* Worker instance */
 type Worker struct {
 	Alive      int               `json:"alive"`
@@ -11,4 +39,184 @@ type Worker struct {
 	Status     string            `json:"status"`
 	Websocket  int               `json:"websocket"`
 	Properties map[string]string `json:"properties"` // Worker properties as returned by openQA
+	JobID      int64             `json:"jobid"`      // ID of the currently running job, if present
+	Tseen      string            `json:"t_seen"`     // Last time the worker was seen, if reported by openQA
+	/* this is added by the program and not part of the fetched json */
+	Link     string `json:"link"`
+	Remote   string `json:"remote"` // openQA remote host
+	instance *Instance
+}
+
+/* Query for filtering workers. Empty fields match all workers */
+type WorkerQuery struct {
+	Host        string   // Host name of the worker
+	Status      []string // Accepted worker states (e.g. "idle", "running", "dead", "broken")
+	WorkerClass []string // Worker classes the worker must provide (all of them)
+}
+
+/* Capacity of all worker slots for a single worker class */
+type WorkerCapacity struct {
+	WorkerClass string `json:"worker_class"`
+	Idle        int    `json:"idle"`    // Slots ready to pick up a job
+	Busy        int    `json:"busy"`    // Slots currently running a job
+	Offline     int    `json:"offline"` // Dead or offline slots
+	Broken      int    `json:"broken"`  // Slots which are connected but report an error
+	Total       int    `json:"total"`
+}
+
+func (w *Worker) applyInstance(i *Instance) {
+	w.Link = fmt.Sprintf("%s/admin/workers/%d", i.URL, w.ID)
+	w.instance = i
+	w.Remote = i.URL
+}
+
+/* Format worker as a string */
+func (w *Worker) String() string {
+	return fmt.Sprintf("%d %s:%d (%s)", w.ID, w.Host, w.Instance, w.Status)
+}
+
+/* WorkerClass returns the parsed WORKER_CLASS property of the worker */
+func (w *Worker) WorkerClass() []string {
+	ret := make([]string, 0)
+	for _, class := range strings.Split(w.Properties["WORKER_CLASS"], ",") {
+		class = strings.TrimSpace(class)
+		if class != "" {
+			ret = append(ret, class)
+		}
+	}
+	return ret
+}
+
+/* HasWorkerClass returns true, if the worker provides all of the given worker classes */
+func (w *Worker) HasWorkerClass(classes ...string) bool {
+	provided := w.WorkerClass()
+	for _, class := range classes {
+		found := false
+		for _, c := range provided {
+			if c == class {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+/* IsBusy returns true, if the worker is currently running a job */
+func (w *Worker) IsBusy() bool {
+	return w.Status == "running" || w.Status == "busy" || w.JobID > 0
+}
+
+/* IsOffline returns true, if the worker is dead or offline */
+func (w *Worker) IsOffline() bool {
+	return w.Status == "dead" || w.Status == "offline"
+}
+
+/* IsBroken returns true, if the worker is connected but reports an error */
+func (w *Worker) IsBroken() bool {
+	return w.Status == "broken"
+}
+
+/* IsIdle returns true, if the worker is ready to pick up a job */
+func (w *Worker) IsIdle() bool {
+	return !w.IsBusy() && !w.IsOffline() && !w.IsBroken()
+}
+
+/* LastSeen returns the time the worker was last seen by openQA */
+func (w *Worker) LastSeen() (time.Time, error) {
+	if w.Tseen == "" {
+		return time.Time{}, fmt.Errorf("unknown")
+	}
+	// openQA returns UTC timestamps, sometimes without the trailing Z
+	return time.Parse(time.RFC3339, strings.TrimSuffix(w.Tseen, "Z")+"Z")
+}
+
+/* OfflineLongerThan returns true, if the worker is offline and has not been seen for at least the given duration */
+func (w *Worker) OfflineLongerThan(duration time.Duration) bool {
+	if !w.IsOffline() {
+		return false
+	}
+	if duration <= 0 {
+		return true
+	}
+	seen, err := w.LastSeen()
+	if err != nil {
+		return false
+	}
+	return time.Since(seen) >= duration
+}
+
+/* FetchJob fetches the currently running job of this worker */
+func (w *Worker) FetchJob() (Job, error) {
+	if w.JobID == 0 {
+		return Job{}, fmt.Errorf("no running job")
+	}
+	if w.instance == nil {
+		return Job{}, fmt.Errorf("no instance assigned")
+	}
+	return w.instance.GetJob(w.JobID)
+}
+
+/* Matches returns true, if the given worker matches the query */
+func (q *WorkerQuery) Matches(w Worker) bool {
+	if q.Host != "" && q.Host != w.Host {
+		return false
+	}
+	if len(q.Status) > 0 {
+		found := false
+		for _, status := range q.Status {
+			if status == w.Status {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return w.HasWorkerClass(q.WorkerClass...)
+}
+
+/* FilterWorkers returns all workers matching the given query */
+func FilterWorkers(workers []Worker, query WorkerQuery) []Worker {
+	ret := make([]Worker, 0)
+	for _, worker := range workers {
+		if query.Matches(worker) {
+			ret = append(ret, worker)
+		}
+	}
+	return ret
+}
+
+/* WorkerCapacityReport groups the given worker slots by their worker class. A worker with multiple classes is counted for each of them */
+func WorkerCapacityReport(workers []Worker) []WorkerCapacity {
+	classes := make(map[string]*WorkerCapacity, 0)
+	for _, worker := range workers {
+		for _, class := range worker.WorkerClass() {
+			capacity, ok := classes[class]
+			if !ok {
+				capacity = &WorkerCapacity{WorkerClass: class}
+				classes[class] = capacity
+			}
+			capacity.Total++
+			if worker.IsOffline() {
+				capacity.Offline++
+			} else if worker.IsBroken() {
+				capacity.Broken++
+			} else if worker.IsBusy() {
+				capacity.Busy++
+			} else {
+				capacity.Idle++
+			}
+		}
+	}
+	ret := make([]WorkerCapacity, 0)
+	for _, capacity := range classes {
+		ret = append(ret, *capacity)
+	}
+	sort.Slice(ret, func(i, j int) bool { return ret[i].WorkerClass < ret[j].WorkerClass })
+	return ret
 }